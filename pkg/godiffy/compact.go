@@ -0,0 +1,317 @@
+package godiffy
+
+// Weights of git's indent heuristic, see xdiff/xdiffi.c.
+const (
+	startOfFilePenalty              = 1
+	endOfFilePenalty                = 21
+	totalBlankWeight                = -30
+	postBlankWeight                 = 6
+	relativeIndentPenalty           = -4
+	relativeIndentWithBlankPenalty  = 10
+	relativeOutdentPenalty          = 24
+	relativeOutdentWithBlankPenalty = 17
+	relativeDedentPenalty           = 23
+	relativeDedentWithBlankPenalty  = 17
+	indentWeight                    = 60
+	indentHeuristicMaxSliding       = 100
+	maxIndent                       = 200
+	maxBlanks                       = 20
+)
+
+// changeGroup is a run [start, end) of changed lines. An empty group sits
+// above the unchanged line at start.
+type changeGroup struct {
+	start, end int
+}
+
+type compactSide struct {
+	ids     []int
+	lines   []string
+	changed []bool // changed[i+1] reports line i, the outer slots are sentinels
+}
+
+func newCompactSide(ids []int, lines []string, changed []bool) *compactSide {
+	padded := make([]bool, len(ids)+2)
+	copy(padded[1:], changed)
+	return &compactSide{ids: ids, lines: lines, changed: padded}
+}
+
+func (s *compactSide) isChanged(i int) bool {
+	return s.changed[i+1]
+}
+
+func (s *compactSide) setChanged(i int, changed bool) {
+	s.changed[i+1] = changed
+}
+
+func (s *compactSide) result() []bool {
+	return s.changed[1 : len(s.ids)+1]
+}
+
+func (s *compactSide) first() changeGroup {
+	g := changeGroup{}
+	for s.isChanged(g.end) {
+		g.end++
+	}
+	return g
+}
+
+func (s *compactSide) next(g *changeGroup) bool {
+	if g.end == len(s.ids) {
+		return false
+	}
+	g.start = g.end + 1
+	for g.end = g.start; s.isChanged(g.end); g.end++ {
+	}
+	return true
+}
+
+func (s *compactSide) previous(g *changeGroup) bool {
+	if g.start == 0 {
+		return false
+	}
+	g.end = g.start - 1
+	for g.start = g.end; s.isChanged(g.start - 1); g.start-- {
+	}
+	return true
+}
+
+func (s *compactSide) slideDown(g *changeGroup) bool {
+	if g.end >= len(s.ids) || s.ids[g.start] != s.ids[g.end] {
+		return false
+	}
+	s.setChanged(g.start, false)
+	s.setChanged(g.end, true)
+	g.start++
+	g.end++
+	for s.isChanged(g.end) {
+		g.end++
+	}
+	return true
+}
+
+func (s *compactSide) slideUp(g *changeGroup) bool {
+	if g.start <= 0 || s.ids[g.start-1] != s.ids[g.end-1] {
+		return false
+	}
+	g.start--
+	g.end--
+	s.setChanged(g.start, true)
+	s.setChanged(g.end, false)
+	for s.isChanged(g.start - 1) {
+		g.start--
+	}
+	return true
+}
+
+// compactChanges slides each group of changed lines in s up and down while
+// the diff stays equivalent, merging groups that touch. The group is then
+// aligned with a change on the other side if possible, or else placed where
+// the indent heuristic likes it best. It mirrors xdl_change_compact.
+func compactChanges(s, o *compactSide, indentHeuristic bool) {
+	g, og := s.first(), o.first()
+
+	for {
+		if g.end != g.start {
+			var groupSize, earliestEnd int
+			for {
+				groupSize = g.end - g.start
+				endMatchingOther := -1
+
+				for s.slideUp(&g) {
+					if !o.previous(&og) {
+						panic("godiffy: group sync broken sliding up")
+					}
+				}
+				earliestEnd = g.end
+				if og.end > og.start {
+					endMatchingOther = g.end
+				}
+
+				for s.slideDown(&g) {
+					if !o.next(&og) {
+						panic("godiffy: group sync broken sliding down")
+					}
+					if og.end > og.start {
+						endMatchingOther = g.end
+					}
+				}
+
+				if groupSize == g.end-g.start {
+					if g.end != earliestEnd {
+						s.placeGroup(o, &g, &og, groupSize, earliestEnd, endMatchingOther, indentHeuristic)
+					}
+					break
+				}
+			}
+		}
+
+		if !s.next(&g) {
+			break
+		}
+		if !o.next(&og) {
+			panic("godiffy: group sync broken moving to next group")
+		}
+	}
+}
+
+func (s *compactSide) placeGroup(o *compactSide, g, og *changeGroup, groupSize, earliestEnd, endMatchingOther int, indentHeuristic bool) {
+	if endMatchingOther != -1 {
+		for og.end == og.start {
+			if !s.slideUp(g) {
+				panic("godiffy: match disappeared")
+			}
+			if !o.previous(og) {
+				panic("godiffy: group sync broken sliding to match")
+			}
+		}
+		return
+	}
+	if !indentHeuristic {
+		return
+	}
+
+	shift := max(earliestEnd, g.end-groupSize-1, g.end-indentHeuristicMaxSliding)
+	bestShift := -1
+	var bestScore splitScore
+	for ; shift <= g.end; shift++ {
+		var score splitScore
+		score.add(s.measureSplit(shift))
+		score.add(s.measureSplit(shift - groupSize))
+		if bestShift == -1 || score.compare(bestScore) <= 0 {
+			bestScore = score
+			bestShift = shift
+		}
+	}
+
+	for g.end > bestShift {
+		if !s.slideUp(g) {
+			panic("godiffy: best shift unreached")
+		}
+		if !o.previous(og) {
+			panic("godiffy: group sync broken sliding to blank line")
+		}
+	}
+}
+
+type splitMeasurement struct {
+	endOfFile  bool
+	indent     int // of the line after the split, -1 if blank
+	preBlank   int // blank lines right above the split
+	preIndent  int // of the nearest non-blank line above, -1 if none
+	postBlank  int // blank lines after the line following the split
+	postIndent int // of the nearest non-blank line after that, -1 if none
+}
+
+type splitScore struct {
+	effectiveIndent int
+	penalty         int
+}
+
+func (s *compactSide) measureSplit(split int) splitMeasurement {
+	m := splitMeasurement{indent: -1, preIndent: -1, postIndent: -1}
+	if split >= len(s.lines) {
+		m.endOfFile = true
+	} else {
+		m.indent = lineIndent(s.lines[split])
+	}
+
+	for i := split - 1; i >= 0; i-- {
+		m.preIndent = lineIndent(s.lines[i])
+		if m.preIndent != -1 {
+			break
+		}
+		m.preBlank++
+		if m.preBlank == maxBlanks {
+			m.preIndent = 0
+			break
+		}
+	}
+
+	for i := split + 1; i < len(s.lines); i++ {
+		m.postIndent = lineIndent(s.lines[i])
+		if m.postIndent != -1 {
+			break
+		}
+		m.postBlank++
+		if m.postBlank == maxBlanks {
+			m.postIndent = 0
+			break
+		}
+	}
+	return m
+}
+
+func (s *splitScore) add(m splitMeasurement) {
+	if m.preIndent == -1 && m.preBlank == 0 {
+		s.penalty += startOfFilePenalty
+	}
+	if m.endOfFile {
+		s.penalty += endOfFilePenalty
+	}
+
+	postBlank := 0
+	if m.indent == -1 {
+		postBlank = 1 + m.postBlank
+	}
+	totalBlank := m.preBlank + postBlank
+	s.penalty += totalBlankWeight * totalBlank
+	s.penalty += postBlankWeight * postBlank
+
+	indent := m.indent
+	if indent == -1 {
+		indent = m.postIndent
+	}
+	anyBlanks := totalBlank != 0
+	s.effectiveIndent += indent
+
+	switch {
+	case indent == -1, m.preIndent == -1, indent == m.preIndent:
+	case indent > m.preIndent:
+		s.penalty += pick(anyBlanks, relativeIndentWithBlankPenalty, relativeIndentPenalty)
+	case m.postIndent != -1 && m.postIndent > indent:
+		// Likely the start of a new block.
+		s.penalty += pick(anyBlanks, relativeOutdentWithBlankPenalty, relativeOutdentPenalty)
+	default:
+		// Likely the end of the previous block.
+		s.penalty += pick(anyBlanks, relativeDedentWithBlankPenalty, relativeDedentPenalty)
+	}
+}
+
+func (s splitScore) compare(other splitScore) int {
+	cmpIndents := 0
+	if s.effectiveIndent > other.effectiveIndent {
+		cmpIndents = 1
+	} else if s.effectiveIndent < other.effectiveIndent {
+		cmpIndents = -1
+	}
+	return indentWeight*cmpIndents + (s.penalty - other.penalty)
+}
+
+// lineIndent returns the indentation width of line with tabs stopping every
+// 8 columns, or -1 if the line is blank.
+func lineIndent(line string) int {
+	indent := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			indent++
+		case '\t':
+			indent += 8 - indent%8
+		case '\n', '\r', '\v', '\f':
+		default:
+			return indent
+		}
+		if indent >= maxIndent {
+			return maxIndent
+		}
+	}
+	return -1
+}
+
+func pick(cond bool, a, b int) int {
+	if cond {
+		return a
+	}
+	return b
+}
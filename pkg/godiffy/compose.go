@@ -0,0 +1,379 @@
+package godiffy
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Compose squashes two diffs, where second was made on top of first, into a
+// single diff with the same effect. Files touched by only one of them are
+// taken over as they are, a file created by first and deleted by second
+// disappears, and renames and copies are followed to their new paths.
+func Compose(first, second *Diff) (*Diff, error) {
+	// Every file of second that works on a file first left behind.
+	bySource := make(map[string][]*FileDiff)
+	for _, file := range second.Files {
+		if file.Status == FileStatusNew {
+			continue
+		}
+		oldPath, _ := file.displayPaths()
+		bySource[oldPath] = append(bySource[oldPath], file)
+	}
+	recreated := make(map[string]*FileDiff)
+	for _, file := range second.Files {
+		if file.Status == FileStatusNew {
+			_, newPath := file.displayPaths()
+			recreated[newPath] = file
+		}
+	}
+
+	used := make(map[*FileDiff]bool)
+	result := &Diff{}
+	for _, f1 := range first.Files {
+		_, path := f1.displayPaths()
+		if f1.Status == FileStatusDeleted {
+			if f2, ok := recreated[path]; ok {
+				used[f2] = true
+				file, err := composeRecreated(f1, f2)
+				if err != nil {
+					return nil, err
+				}
+				if file != nil {
+					result.Files = append(result.Files, file)
+				}
+				continue
+			}
+			result.Files = append(result.Files, f1)
+			continue
+		}
+
+		kept := true
+		for _, f2 := range bySource[path] {
+			used[f2] = true
+			if f2.Status != FileStatusCopied {
+				kept = false
+			}
+			file, err := composeFile(f1, f2)
+			if err != nil {
+				return nil, err
+			}
+			if file != nil {
+				result.Files = append(result.Files, file)
+			}
+		}
+		if kept {
+			result.Files = append(result.Files, f1)
+		}
+	}
+	for _, f2 := range second.Files {
+		if !used[f2] {
+			result.Files = append(result.Files, f2)
+		}
+	}
+
+	slices.SortStableFunc(result.Files, func(a, b *FileDiff) int {
+		_, pathA := a.displayPaths()
+		_, pathB := b.displayPaths()
+		return comparePaths(pathA, pathB)
+	})
+	return result, nil
+}
+
+// composeFile combines a change of first with a change of second that
+// starts from the file first produced. It returns nil when the two cancel
+// out.
+func composeFile(f1, f2 *FileDiff) (*FileDiff, error) {
+	oldPath, path := f1.displayPaths()
+	_, newPath := f2.displayPaths()
+	if (f1.IsBinary && len(f1.Hunks) == 0) || (f2.IsBinary && len(f2.Hunks) == 0) {
+		return nil, fmt.Errorf("cannot compose binary changes of %s", path)
+	}
+	hunks, err := composeHunks(f1.Hunks, f2.Hunks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compose changes of %s: %w", path, err)
+	}
+
+	file := &FileDiff{
+		OldHash: f1.OldHash,
+		NewHash: f2.NewHash,
+		OldPath: oldPath,
+		NewPath: newPath,
+		OldMode: f1.OldMode,
+		NewMode: f2.NewMode,
+		Hunks:   hunks,
+	}
+	if file.NewMode == "" {
+		file.NewMode = f1.NewMode
+	}
+
+	switch {
+	case f1.Status == FileStatusNew && f2.Status == FileStatusDeleted:
+		return nil, nil
+	case f1.Status == FileStatusCopied && f2.Status == FileStatusDeleted:
+		return nil, nil
+	case f2.Status == FileStatusDeleted:
+		file.Status, file.NewPath, file.NewHash, file.NewMode = FileStatusDeleted, oldPath, f2.NewHash, ""
+		if file.OldMode == "" {
+			file.OldMode = f2.OldMode
+		}
+	case f1.Status == FileStatusNew:
+		file.Status, file.OldPath = FileStatusNew, newPath
+	case f1.Status == FileStatusCopied || f2.Status == FileStatusCopied:
+		file.Status = FileStatusCopied
+	case oldPath != newPath:
+		file.Status = FileStatusRenamed
+	default:
+		file.Status = FileStatusModified
+		if len(hunks) == 0 && (file.OldMode == "" || file.OldMode == file.NewMode) {
+			return nil, nil
+		}
+	}
+	if file.Status == FileStatusRenamed || file.Status == FileStatusCopied {
+		file.OldName, file.NewName = file.OldPath, file.NewPath
+	}
+	if file.OldMode == file.NewMode && file.Status != FileStatusNew {
+		file.OldMode = ""
+	}
+	if f1.Header != "" || f2.Header != "" {
+		file.Header = file.headerLine()
+	}
+	return file, nil
+}
+
+// composeRecreated combines the deletion of a file with its recreation,
+// which leaves a modified file, or nothing if the content came back as it
+// was.
+func composeRecreated(deleted, created *FileDiff) (*FileDiff, error) {
+	_, path := deleted.displayPaths()
+	if (deleted.IsBinary && len(deleted.Hunks) == 0) || (created.IsBinary && len(created.Hunks) == 0) {
+		return nil, fmt.Errorf("cannot compose binary changes of %s", path)
+	}
+	oldText, newText := hunkText(deleted.Hunks, HunkLineDeleted), hunkText(created.Hunks, HunkLineAdded)
+	if oldText == newText && deleted.OldMode == created.NewMode {
+		return nil, nil
+	}
+	computed, err := Compute(oldText, newText, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compose changes of %s: %w", path, err)
+	}
+	file := &FileDiff{
+		OldHash: deleted.OldHash,
+		NewHash: created.NewHash,
+		OldPath: path,
+		NewPath: path,
+		OldMode: deleted.OldMode,
+		NewMode: created.NewMode,
+		Status:  FileStatusModified,
+		Hunks:   computed.Hunks,
+	}
+	if file.OldMode == file.NewMode {
+		file.OldMode = ""
+	}
+	if deleted.Header != "" || created.Header != "" {
+		file.Header = file.headerLine()
+	}
+	return file, nil
+}
+
+func hunkText(hunks []*Hunk, kind HunkLineKind) string {
+	var text string
+	for _, hunk := range hunks {
+		for _, line := range hunk.Lines {
+			if line.Type == kind {
+				text += line.Content
+			}
+		}
+	}
+	return text
+}
+
+// editOp is one step of a diff read as an edit script over the whole file.
+// Unchanged lines outside the hunks have unknown content and are kept in
+// runs of count lines.
+type editOp struct {
+	kind    HunkLineKind
+	content string
+	known   bool
+	count   int
+}
+
+func editScript(hunks []*Hunk) ([]editOp, error) {
+	var ops []editOp
+	pos := 0
+	for i, hunk := range hunks {
+		start := firstLineIndex(hunk.OldStart, hunk.OldLineCount)
+		if start < pos {
+			return nil, fmt.Errorf("hunk #%d overlaps the previous one", i+1)
+		}
+		if start > pos {
+			ops = append(ops, editOp{kind: HunkLineContext, count: start - pos})
+		}
+		pos = start
+		for _, line := range hunk.Lines {
+			ops = append(ops, editOp{kind: line.Type, content: line.Content, known: true, count: 1})
+			if line.Type != HunkLineAdded {
+				pos++
+			}
+		}
+	}
+	return ops, nil
+}
+
+// editCursor walks an edit script. Past its end it keeps unknown lines
+// forever.
+type editCursor struct {
+	ops  []editOp
+	i    int
+	used int
+}
+
+func (c *editCursor) done() bool {
+	return c.i == len(c.ops)
+}
+
+func (c *editCursor) peek() editOp {
+	if c.done() {
+		return editOp{kind: HunkLineContext, count: -1}
+	}
+	op := c.ops[c.i]
+	op.count -= c.used
+	return op
+}
+
+func (c *editCursor) advance(n int) {
+	if c.done() {
+		return
+	}
+	c.used += n
+	if c.used == c.ops[c.i].count {
+		c.i++
+		c.used = 0
+	}
+}
+
+// composeHunks chains the edit scripts of two diffs: the lines the first
+// one keeps or adds are what the second one keeps or deletes.
+func composeHunks(first, second []*Hunk) ([]*Hunk, error) {
+	ops1, err := editScript(first)
+	if err != nil {
+		return nil, err
+	}
+	ops2, err := editScript(second)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []editOp
+	c1, c2 := &editCursor{ops: ops1}, &editCursor{ops: ops2}
+	line := 0 // 0-based line of the intermediate file
+	for !c1.done() || !c2.done() {
+		o1, o2 := c1.peek(), c2.peek()
+		if o2.kind == HunkLineAdded {
+			result = append(result, o2)
+			c2.advance(1)
+			continue
+		}
+		if o1.kind == HunkLineDeleted {
+			result = append(result, o1)
+			c1.advance(1)
+			continue
+		}
+
+		if !o1.known && !o2.known {
+			n := o1.count
+			if n == -1 || (o2.count != -1 && o2.count < n) {
+				n = o2.count
+			}
+			result = append(result, editOp{kind: HunkLineContext, count: n})
+			c1.advance(n)
+			c2.advance(n)
+			line += n
+			continue
+		}
+		if o1.known && o2.known && o1.content != o2.content {
+			return nil, fmt.Errorf("second diff does not match the result of the first at line %d", line+1)
+		}
+		content := o1.content
+		if !o1.known {
+			content = o2.content
+		}
+		switch {
+		case o1.kind == HunkLineAdded && o2.kind == HunkLineDeleted:
+		case o1.kind == HunkLineAdded:
+			result = append(result, editOp{kind: HunkLineAdded, content: content, known: true, count: 1})
+		case o2.kind == HunkLineDeleted:
+			result = append(result, editOp{kind: HunkLineDeleted, content: content, known: true, count: 1})
+		default:
+			result = append(result, editOp{kind: HunkLineContext, content: content, known: true, count: 1})
+		}
+		c1.advance(1)
+		c2.advance(1)
+		line++
+	}
+	return scriptHunks(result, DefaultContext), nil
+}
+
+// scriptHunks turns an edit script back into hunks with up to context lines
+// of context, as far as the content of those lines is known.
+func scriptHunks(ops []editOp, context int) []*Hunk {
+	type scriptLine struct {
+		line           *HunkLine
+		oldIdx, newIdx int
+	}
+	var hunks []*Hunk
+	var current []scriptLine
+	var pending []scriptLine // known context since the last change
+	open := false
+
+	flush := func() {
+		if !open {
+			return
+		}
+		current = append(current, pending[:min(context, len(pending))]...)
+		hunk := &Hunk{}
+		for _, l := range current {
+			hunk.Lines = append(hunk.Lines, l.line)
+		}
+		hunk.recount()
+		hunk.OldStart = hunkStart(current[0].oldIdx, hunk.OldLineCount)
+		hunk.NewStart = hunkStart(current[0].newIdx, hunk.NewLineCount)
+		hunks = append(hunks, hunk)
+		current, open = nil, false
+	}
+
+	oldIdx, newIdx := 0, 0
+	for _, op := range ops {
+		if !op.known {
+			flush()
+			pending = nil
+			oldIdx += op.count
+			newIdx += op.count
+			continue
+		}
+		l := scriptLine{line: &HunkLine{Type: op.kind, Content: op.content}, oldIdx: oldIdx, newIdx: newIdx}
+		switch op.kind {
+		case HunkLineContext:
+			pending = append(pending, l)
+			oldIdx++
+			newIdx++
+			continue
+		case HunkLineDeleted:
+			oldIdx++
+		case HunkLineAdded:
+			newIdx++
+		}
+		if open && len(pending) > 2*context {
+			flush()
+		}
+		if open {
+			current = append(current, pending...)
+		} else {
+			current = append(current, pending[max(len(pending)-context, 0):]...)
+			open = true
+		}
+		current = append(current, l)
+		pending = nil
+	}
+	flush()
+	return hunks
+}
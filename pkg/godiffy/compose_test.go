@@ -0,0 +1,174 @@
+package godiffy
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// applyTestHunks applies hunks at their exact positions.
+func applyTestHunks(t *testing.T, text string, hunks []*Hunk) string {
+	t.Helper()
+	ops, err := editScript(hunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := splitLines(text)
+	var b strings.Builder
+	pos := 0
+	for _, op := range ops {
+		switch {
+		case !op.known:
+			for _, line := range lines[pos : pos+op.count] {
+				b.WriteString(line)
+			}
+			pos += op.count
+		case op.kind == HunkLineAdded:
+			b.WriteString(op.content)
+		default:
+			if lines[pos] != op.content {
+				t.Fatalf("line %d is %q, hunk expects %q", pos+1, lines[pos], op.content)
+			}
+			if op.kind == HunkLineContext {
+				b.WriteString(op.content)
+			}
+			pos++
+		}
+	}
+	for _, line := range lines[pos:] {
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+func randomEdit(r *rand.Rand, lines []string) []string {
+	var result []string
+	for _, line := range lines {
+		switch r.Intn(8) {
+		case 0:
+		case 1:
+			result = append(result, string(rune('a'+r.Intn(4)))+"\n")
+		case 2:
+			result = append(result, line, string(rune('a'+r.Intn(4)))+"\n")
+		default:
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+func TestComposeHunksRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		var a []string
+		for j := r.Intn(30); j > 0; j-- {
+			a = append(a, string(rune('a'+r.Intn(6)))+"\n")
+		}
+		b := randomEdit(r, a)
+		c := randomEdit(r, b)
+		textA, textB, textC := strings.Join(a, ""), strings.Join(b, ""), strings.Join(c, "")
+
+		ab, err := Compute(textA, textB, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bc, err := Compute(textB, textC, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hunks, err := composeHunks(ab.Hunks, bc.Hunks)
+		if err != nil {
+			t.Fatalf("case %d: composeHunks returned error: %v", i, err)
+		}
+		if got := applyTestHunks(t, textA, hunks); got != textC {
+			t.Fatalf("case %d: composed diff gives %q, want %q", i, got, textC)
+		}
+	}
+}
+
+func TestComposeOverlappingHunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n"
+	c := "1\n2\nTHREE\nfour\n5\n6\n7\n8\n9\n10\n"
+	ab, _ := Compute(a, b, nil)
+	bc, _ := Compute(b, c, nil)
+	hunks, err := composeHunks(ab.Hunks, bc.Hunks)
+	if err != nil {
+		t.Fatalf("composeHunks returned error: %v", err)
+	}
+	want := "@@ -1,7 +1,7 @@\n 1\n 2\n-3\n-4\n+THREE\n+four\n 5\n 6\n 7\n"
+	if got := hunksString(&FileDiff{Hunks: hunks}); got != want {
+		t.Errorf("composed =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestComposeMismatch(t *testing.T) {
+	ab, _ := Compute("a\nb\nc\n", "a\nB\nc\n", nil)
+	bc, _ := Compute("a\nx\nc\n", "a\ny\nc\n", nil)
+	if _, err := composeHunks(ab.Hunks, bc.Hunks); err == nil {
+		t.Fatal("expected error for diffs that do not chain, got nil")
+	}
+}
+
+func composeTestFile(t *testing.T, status FileStatus, oldPath, newPath, oldText, newText string) *FileDiff {
+	t.Helper()
+	file, err := Compute(oldText, newText, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Status, file.OldPath, file.NewPath = status, oldPath, newPath
+	switch status {
+	case FileStatusNew:
+		file.NewMode = "100644"
+	case FileStatusDeleted:
+		file.OldMode = "100644"
+	case FileStatusRenamed:
+		file.OldName, file.NewName = oldPath, newPath
+	}
+	return file
+}
+
+func TestComposeFiles(t *testing.T) {
+	first := &Diff{Files: []*FileDiff{
+		composeTestFile(t, FileStatusNew, "created.txt", "created.txt", "", "a\nb\n"),
+		composeTestFile(t, FileStatusNew, "temp.txt", "temp.txt", "", "x\n"),
+		composeTestFile(t, FileStatusRenamed, "old.txt", "moved.txt", "1\n2\n3\n", "1\n2\n3\n"),
+		composeTestFile(t, FileStatusDeleted, "again.txt", "again.txt", "same\n", ""),
+		composeTestFile(t, FileStatusModified, "only-first.txt", "only-first.txt", "p\n", "q\n"),
+	}}
+	second := &Diff{Files: []*FileDiff{
+		composeTestFile(t, FileStatusModified, "created.txt", "created.txt", "a\nb\n", "a\nB\n"),
+		composeTestFile(t, FileStatusDeleted, "temp.txt", "temp.txt", "x\n", ""),
+		composeTestFile(t, FileStatusModified, "moved.txt", "moved.txt", "1\n2\n3\n", "1\ntwo\n3\n"),
+		composeTestFile(t, FileStatusNew, "again.txt", "again.txt", "", "same\n"),
+		composeTestFile(t, FileStatusNew, "only-second.txt", "only-second.txt", "", "z\n"),
+	}}
+	second.Files[3].NewMode = "100644"
+
+	composed, err := Compose(first, second)
+	if err != nil {
+		t.Fatalf("Compose returned error: %v", err)
+	}
+	want := []struct {
+		status           FileStatus
+		oldPath, newPath string
+		text             string
+	}{
+		{FileStatusNew, "created.txt", "created.txt", "@@ -0,0 +1,2 @@\n+a\n+B\n"},
+		{FileStatusRenamed, "old.txt", "moved.txt", "@@ -1,3 +1,3 @@\n 1\n-2\n+two\n 3\n"},
+		{FileStatusModified, "only-first.txt", "only-first.txt", "@@ -1 +1 @@\n-p\n+q\n"},
+		{FileStatusNew, "only-second.txt", "only-second.txt", "@@ -0,0 +1 @@\n+z\n"},
+	}
+	if len(composed.Files) != len(want) {
+		t.Fatalf("expected %d files, got %d:\n%s", len(want), len(composed.Files), composed.String())
+	}
+	for i, w := range want {
+		f := composed.Files[i]
+		if f.Status != w.status || f.OldPath != w.oldPath || f.NewPath != w.newPath {
+			t.Errorf("file %d: got status %d %s -> %s, want status %d %s -> %s", i, f.Status, f.OldPath, f.NewPath, w.status, w.oldPath, w.newPath)
+		}
+		if got := hunksString(f); got != w.text {
+			t.Errorf("file %d hunks =\n%s\nwant\n%s", i, got, w.text)
+		}
+	}
+}
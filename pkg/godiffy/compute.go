@@ -0,0 +1,206 @@
+package godiffy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const DefaultContext = 3
+
+// funcNameMaxLen caps the hunk section text, like git's 80 byte buffer.
+const funcNameMaxLen = 80
+
+func DefaultDiffOptions() *DiffOptions {
+	return &DiffOptions{
+		Context:         DefaultContext,
+		IndentHeuristic: true,
+	}
+}
+
+// Compute diffs two texts line by line with the selected algorithm and
+// returns the changes as a modified FileDiff. Paths, hashes and modes are
+// left for the caller to fill in. A nil opts uses DefaultDiffOptions.
+func Compute(oldText, newText string, opts *DiffOptions) (*FileDiff, error) {
+	if opts == nil {
+		opts = DefaultDiffOptions()
+	}
+	if opts.Context < 0 {
+		return nil, fmt.Errorf("invalid context line count: %d", opts.Context)
+	}
+
+	var algorithm func(a, b []int) ([]bool, []bool)
+	switch opts.Algorithm {
+	case AlgorithmMyers:
+		algorithm = myersDiff
+	case AlgorithmPatience:
+		algorithm = patienceDiff
+	case AlgorithmHistogram:
+		algorithm = histogramDiff
+	default:
+		return nil, fmt.Errorf("unknown diff algorithm: %d", opts.Algorithm)
+	}
+
+	oldLines, newLines := splitLines(oldText), splitLines(newText)
+	oldIDs, newIDs := classifyLines(oldLines, newLines)
+	oldChanged, newChanged := algorithm(oldIDs, newIDs)
+
+	oldSide := newCompactSide(oldIDs, oldLines, oldChanged)
+	newSide := newCompactSide(newIDs, newLines, newChanged)
+	compactChanges(oldSide, newSide, opts.IndentHeuristic)
+	compactChanges(newSide, oldSide, opts.IndentHeuristic)
+
+	file := &FileDiff{
+		Status: FileStatusModified,
+		Hunks:  buildHunks(oldLines, newLines, oldSide.result(), newSide.result(), opts.Context),
+	}
+	return file, nil
+}
+
+func splitLines(text string) []string {
+	var lines []string
+	for line := range strings.Lines(text) {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// classifyLines assigns every distinct line an id so that the algorithms can
+// compare integers instead of strings.
+func classifyLines(oldLines, newLines []string) (oldIDs, newIDs []int) {
+	ids := make(map[string]int)
+	classify := func(lines []string) []int {
+		result := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			result[i] = id
+		}
+		return result
+	}
+	return classify(oldLines), classify(newLines)
+}
+
+// change is a single edit: chg1 lines at i1 in the old file were replaced by
+// chg2 lines at i2 in the new file.
+type change struct {
+	i1, i2     int
+	chg1, chg2 int
+}
+
+func buildScript(oldChanged, newChanged []bool) []change {
+	var script []change
+	i1, i2 := 0, 0
+	for i1 < len(oldChanged) || i2 < len(newChanged) {
+		if !isChanged(oldChanged, i1) && !isChanged(newChanged, i2) {
+			i1++
+			i2++
+			continue
+		}
+		c := change{i1: i1, i2: i2}
+		for isChanged(oldChanged, i1) {
+			i1++
+		}
+		for isChanged(newChanged, i2) {
+			i2++
+		}
+		c.chg1, c.chg2 = i1-c.i1, i2-c.i2
+		script = append(script, c)
+	}
+	return script
+}
+
+func isChanged(changed []bool, i int) bool {
+	return i >= 0 && i < len(changed) && changed[i]
+}
+
+// buildHunks groups the changes into hunks with the given amount of context.
+// Changes closer than twice the context end up in the same hunk.
+func buildHunks(oldLines, newLines []string, oldChanged, newChanged []bool, context int) []*Hunk {
+	script := buildScript(oldChanged, newChanged)
+	var hunks []*Hunk
+	funcLine, funcLinePrev := "", -1
+
+	for first := 0; first < len(script); {
+		last := first
+		for last+1 < len(script) && script[last+1].i1-(script[last].i1+script[last].chg1) <= 2*context {
+			last++
+		}
+		xch, xche := script[first], script[last]
+
+		s1 := max(xch.i1-context, 0)
+		s2 := max(xch.i2-context, 0)
+		lctx := min(context, len(oldLines)-(xche.i1+xche.chg1), len(newLines)-(xche.i2+xche.chg2))
+		e1 := xche.i1 + xche.chg1 + lctx
+		e2 := xche.i2 + xche.chg2 + lctx
+
+		if name, ok := findFuncLine(oldLines, s1-1, funcLinePrev); ok {
+			funcLine = name
+		}
+		funcLinePrev = s1 - 1
+
+		hunk := &Hunk{
+			OldStart:     hunkStart(s1, e1-s1),
+			NewStart:     hunkStart(s2, e2-s2),
+			OldLineCount: e1 - s1,
+			NewLineCount: e2 - s2,
+			Section:      funcLine,
+		}
+		for ; s2 < xch.i2; s2++ {
+			hunk.Lines = append(hunk.Lines, &HunkLine{Type: HunkLineContext, Content: newLines[s2]})
+		}
+		s1, s2 = xch.i1, xch.i2
+		for k := first; k <= last; k++ {
+			c := script[k]
+			for ; s1 < c.i1 && s2 < c.i2; s1, s2 = s1+1, s2+1 {
+				hunk.Lines = append(hunk.Lines, &HunkLine{Type: HunkLineContext, Content: newLines[s2]})
+			}
+			for _, line := range oldLines[c.i1 : c.i1+c.chg1] {
+				hunk.Lines = append(hunk.Lines, &HunkLine{Type: HunkLineDeleted, Content: line})
+			}
+			for _, line := range newLines[c.i2 : c.i2+c.chg2] {
+				hunk.Lines = append(hunk.Lines, &HunkLine{Type: HunkLineAdded, Content: line})
+			}
+			s1, s2 = c.i1+c.chg1, c.i2+c.chg2
+		}
+		for ; s2 < e2; s2++ {
+			hunk.Lines = append(hunk.Lines, &HunkLine{Type: HunkLineContext, Content: newLines[s2]})
+		}
+
+		hunks = append(hunks, hunk)
+		first = last + 1
+	}
+	return hunks
+}
+
+// hunkStart converts a 0-based line index into the 1-based number shown in
+// a hunk header. Empty ranges name the line before them, so they start at 0
+// for an empty file.
+func hunkStart(index, count int) int {
+	if count == 0 {
+		return index
+	}
+	return index + 1
+}
+
+// findFuncLine looks backwards from start, stopping at limit, for a line
+// that starts with a letter, '_' or '$', which is git's default notion of a
+// function header.
+func findFuncLine(lines []string, start, limit int) (string, bool) {
+	for l := start; l != limit && l >= 0 && l < len(lines); l-- {
+		line := lines[l]
+		if line == "" {
+			continue
+		}
+		if c := line[0]; c < unicode.MaxASCII && (unicode.IsLetter(rune(c)) || c == '_' || c == '$') {
+			if len(line) > funcNameMaxLen {
+				line = line[:funcNameMaxLen]
+			}
+			return strings.TrimRight(line, " \t\n\v\f\r"), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,289 @@
+package godiffy
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func hunksString(file *FileDiff) string {
+	var b strings.Builder
+	for _, hunk := range file.Hunks {
+		b.WriteString(hunk.String())
+	}
+	return b.String()
+}
+
+func TestComputeSingleChange(t *testing.T) {
+	file, err := Compute("line1\nline2\nline3\n", "line1\nnew2\nline3\nline4\n", nil)
+	if err != nil {
+		t.Fatalf("Compute returned error: %v", err)
+	}
+	if file.Status != FileStatusModified {
+		t.Errorf("expected status %d, got %d", FileStatusModified, file.Status)
+	}
+	if len(file.Hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(file.Hunks))
+	}
+
+	h := file.Hunks[0]
+	if h.OldStart != 1 || h.OldLineCount != 3 || h.NewStart != 1 || h.NewLineCount != 4 {
+		t.Errorf("hunk range = -%d,%d +%d,%d, want -1,3 +1,4", h.OldStart, h.OldLineCount, h.NewStart, h.NewLineCount)
+	}
+	want := []HunkLine{
+		{Type: HunkLineContext, Content: "line1\n"},
+		{Type: HunkLineDeleted, Content: "line2\n"},
+		{Type: HunkLineAdded, Content: "new2\n"},
+		{Type: HunkLineContext, Content: "line3\n"},
+		{Type: HunkLineAdded, Content: "line4\n"},
+	}
+	if len(h.Lines) != len(want) {
+		t.Fatalf("expected %d lines, got %d", len(want), len(h.Lines))
+	}
+	for i, line := range h.Lines {
+		if *line != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, *line, want[i])
+		}
+	}
+}
+
+func TestComputeIdentical(t *testing.T) {
+	file, err := Compute("a\nb\n", "a\nb\n", nil)
+	if err != nil {
+		t.Fatalf("Compute returned error: %v", err)
+	}
+	if len(file.Hunks) != 0 {
+		t.Errorf("expected no hunks, got %d", len(file.Hunks))
+	}
+}
+
+func TestComputeContextLines(t *testing.T) {
+	var oldText, newText strings.Builder
+	for i := 1; i <= 20; i++ {
+		line := strconv.Itoa(i) + "\n"
+		oldText.WriteString(line)
+		switch i {
+		case 3:
+			newText.WriteString("three\n")
+		case 15:
+			newText.WriteString("fifteen\n")
+		default:
+			newText.WriteString(line)
+		}
+	}
+
+	tests := []struct {
+		context int
+		want    []string
+	}{
+		{0, []string{"@@ -3 +3 @@", "@@ -15 +15 @@"}},
+		{1, []string{"@@ -2,3 +2,3 @@", "@@ -14,3 +14,3 @@"}},
+		{3, []string{"@@ -1,6 +1,6 @@", "@@ -12,7 +12,7 @@"}},
+		{6, []string{"@@ -1,20 +1,20 @@"}},
+	}
+	for _, tt := range tests {
+		opts := DefaultDiffOptions()
+		opts.Context = tt.context
+		file, err := Compute(oldText.String(), newText.String(), opts)
+		if err != nil {
+			t.Fatalf("Compute returned error: %v", err)
+		}
+		var got []string
+		for _, hunk := range file.Hunks {
+			header, _, _ := strings.Cut(hunk.String(), "\n")
+			got = append(got, header)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("context %d: headers = %q, want %q", tt.context, got, tt.want)
+		}
+	}
+}
+
+func TestComputeNoNewlineAtEOF(t *testing.T) {
+	file, err := Compute("a\nb\nc\n", "a\nB\nc", nil)
+	if err != nil {
+		t.Fatalf("Compute returned error: %v", err)
+	}
+	want := `@@ -1,3 +1,3 @@
+ a
+-b
+-c
++B
++c
+\ No newline at end of file
+`
+	if got := hunksString(file); got != want {
+		t.Errorf("hunks =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestComputeEmptySides(t *testing.T) {
+	file, err := Compute("", "one\n", nil)
+	if err != nil {
+		t.Fatalf("Compute returned error: %v", err)
+	}
+	if got, want := hunksString(file), "@@ -0,0 +1 @@\n+one\n"; got != want {
+		t.Errorf("hunks = %q, want %q", got, want)
+	}
+
+	file, err = Compute("one\n", "", nil)
+	if err != nil {
+		t.Fatalf("Compute returned error: %v", err)
+	}
+	if got, want := hunksString(file), "@@ -1 +0,0 @@\n-one\n"; got != want {
+		t.Errorf("hunks = %q, want %q", got, want)
+	}
+}
+
+func TestComputeFunctionSection(t *testing.T) {
+	oldText := "func a() {\n\tx := 1\n\ty := 2\n\tz := 3\n\treturn\n}\n"
+	newText := "func a() {\n\tx := 1\n\ty := 2\n\tz := 4\n\treturn\n}\n"
+	opts := DefaultDiffOptions()
+	opts.Context = 1
+	file, err := Compute(oldText, newText, opts)
+	if err != nil {
+		t.Fatalf("Compute returned error: %v", err)
+	}
+	if len(file.Hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(file.Hunks))
+	}
+	if file.Hunks[0].Section != "func a() {" {
+		t.Errorf("expected section 'func a() {', got '%s'", file.Hunks[0].Section)
+	}
+}
+
+func TestComputeIndentHeuristic(t *testing.T) {
+	oldText := "1\n2\na\n\nb\n3\n4\n"
+	newText := "1\n2\na\n\nb\na\n\nb\n3\n4\n"
+
+	// Both placements are valid, the heuristic picks the one git shows.
+	file, err := Compute(oldText, newText, nil)
+	if err != nil {
+		t.Fatalf("Compute returned error: %v", err)
+	}
+	want := "@@ -2,6 +2,9 @@\n 2\n a\n \n+b\n+a\n+\n b\n 3\n 4\n"
+	if got := hunksString(file); got != want {
+		t.Errorf("with heuristic hunks =\n%s\nwant\n%s", got, want)
+	}
+
+	opts := DefaultDiffOptions()
+	opts.IndentHeuristic = false
+	file, err = Compute(oldText, newText, opts)
+	if err != nil {
+		t.Fatalf("Compute returned error: %v", err)
+	}
+	want = "@@ -3,5 +3,8 @@\n a\n \n b\n+a\n+\n+b\n 3\n 4\n"
+	if got := hunksString(file); got != want {
+		t.Errorf("without heuristic hunks =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestComputeInvalidContext(t *testing.T) {
+	_, err := Compute("a\n", "b\n", &DiffOptions{Context: -1})
+	if err == nil {
+		t.Fatal("expected error for negative context, got nil")
+	}
+}
+
+func TestComputeParseRoundTrip(t *testing.T) {
+	oldText := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+	newText := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}"
+	file, err := Compute(oldText, newText, nil)
+	if err != nil {
+		t.Fatalf("Compute returned error: %v", err)
+	}
+	file.OldPath, file.NewPath = "main.go", "main.go"
+
+	diff, err := Parse((&Diff{Files: []*FileDiff{file}}).String())
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(diff.Files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(diff.Files))
+	}
+	if got, want := hunksString(diff.Files[0]), hunksString(file); got != want {
+		t.Errorf("round trip hunks =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// The corpus holds real changes from this repository together with the hunks
+// "git diff --diff-algorithm=<name>" printed for them.
+func TestComputeMatchesGitCorpus(t *testing.T) {
+	algorithms := map[string]Algorithm{
+		"myers":     AlgorithmMyers,
+		"patience":  AlgorithmPatience,
+		"histogram": AlgorithmHistogram,
+	}
+
+	dirs, err := filepath.Glob(filepath.Join("testdata", "corpus", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) == 0 {
+		t.Fatal("expected corpus directories, got none")
+	}
+	for _, dir := range dirs {
+		oldText, err := os.ReadFile(filepath.Join(dir, "old.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		newText, err := os.ReadFile(filepath.Join(dir, "new.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, algorithm := range algorithms {
+			want, err := os.ReadFile(filepath.Join(dir, name+".diff"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := DefaultDiffOptions()
+			opts.Algorithm = algorithm
+			file, err := Compute(string(oldText), string(newText), opts)
+			if err != nil {
+				t.Fatalf("Compute returned error: %v", err)
+			}
+			if got := hunksString(file); got != string(want) {
+				t.Errorf("%s/%s: hunks =\n%s\nwant\n%s", filepath.Base(dir), name, got, want)
+			}
+		}
+	}
+}
+
+func TestComputeAlgorithmsDiffer(t *testing.T) {
+	dir := filepath.Join("testdata", "corpus", "test-reorder")
+	oldText, err := os.ReadFile(filepath.Join(dir, "old.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newText, err := os.ReadFile(filepath.Join(dir, "new.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Moving a whole test function makes Myers match up braces and blank
+	// lines, while patience and histogram show the move as one block.
+	count := func(algorithm Algorithm) int {
+		opts := DefaultDiffOptions()
+		opts.Algorithm = algorithm
+		file, err := Compute(string(oldText), string(newText), opts)
+		if err != nil {
+			t.Fatalf("Compute returned error: %v", err)
+		}
+		return len(file.Hunks)
+	}
+	if myers, patience := count(AlgorithmMyers), count(AlgorithmPatience); myers <= patience {
+		t.Errorf("expected myers to produce more hunks than patience, got %d and %d", myers, patience)
+	}
+	if myers, histogram := count(AlgorithmMyers), count(AlgorithmHistogram); myers <= histogram {
+		t.Errorf("expected myers to produce more hunks than histogram, got %d and %d", myers, histogram)
+	}
+}
+
+func TestComputeUnknownAlgorithm(t *testing.T) {
+	_, err := Compute("a\n", "b\n", &DiffOptions{Algorithm: Algorithm(42), Context: DefaultContext})
+	if err == nil {
+		t.Fatal("expected error for unknown algorithm, got nil")
+	}
+}
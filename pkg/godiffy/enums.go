@@ -7,6 +7,8 @@ const (
 	FileStatusRenamed
 	FileStatusCopied
 	FileStatusUnknown
+	FileStatusTypeChanged
+	FileStatusUnmerged
 )
 
 const (
@@ -14,3 +16,32 @@ const (
 	HunkLineDeleted
 	HunkLineContext
 )
+
+const (
+	LineEndingNone LineEnding = iota // the last line of a file without a newline
+	LineEndingLF
+	LineEndingCRLF
+)
+
+const (
+	AlgorithmMyers Algorithm = iota
+	AlgorithmPatience
+	AlgorithmHistogram
+)
+
+const (
+	ObjectFormatSHA1 ObjectFormat = iota
+	ObjectFormatSHA256
+)
+
+const (
+	IntralineModeWord IntralineMode = iota
+	IntralineModeChar
+)
+
+const (
+	InterdiffUnchanged InterdiffStatus = iota
+	InterdiffAdded
+	InterdiffDropped
+	InterdiffModified
+)
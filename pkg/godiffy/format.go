@@ -0,0 +1,172 @@
+package godiffy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const noNewlineMarker = "\\ No newline at end of file\n"
+
+// String renders the diff in git's unified format, so that Parse can read
+// it back.
+func (d *Diff) String() string {
+	var b strings.Builder
+	for _, file := range d.Files {
+		b.WriteString(file.String())
+	}
+	return b.String()
+}
+
+func (f *FileDiff) String() string {
+	var b strings.Builder
+	oldPath, newPath := f.displayPaths()
+	if f.Submodule != nil && f.Submodule.Summary {
+		return f.Submodule.summaryString(oldPath)
+	}
+	b.WriteString(f.headerLine())
+
+	switch {
+	case f.Status == FileStatusNew:
+		fmt.Fprintf(&b, "new file mode %s\n", f.NewMode)
+	case f.Status == FileStatusDeleted:
+		fmt.Fprintf(&b, "deleted file mode %s\n", f.OldMode)
+	case f.OldMode != "" && f.NewMode != "" && f.OldMode != f.NewMode:
+		fmt.Fprintf(&b, "old mode %s\nnew mode %s\n", f.OldMode, f.NewMode)
+	}
+	if f.DissimilarityIndex != "" && f.Status == FileStatusModified {
+		fmt.Fprintf(&b, "dissimilarity index %s\n", f.DissimilarityIndex)
+	}
+
+	switch f.Status {
+	case FileStatusRenamed:
+		if f.SimilarityIndex != "" {
+			fmt.Fprintf(&b, "similarity index %s\n", f.SimilarityIndex)
+		}
+		fmt.Fprintf(&b, "rename from %s\nrename to %s\n", quotePath(oldPath), quotePath(newPath))
+	case FileStatusCopied:
+		if f.SimilarityIndex != "" {
+			fmt.Fprintf(&b, "similarity index %s\n", f.SimilarityIndex)
+		}
+		fmt.Fprintf(&b, "copy from %s\ncopy to %s\n", quotePath(oldPath), quotePath(newPath))
+	}
+
+	if f.OldHash != "" || f.NewHash != "" {
+		fmt.Fprintf(&b, "index %s..%s", f.OldHash, f.NewHash)
+		if mode := f.indexMode(); mode != "" {
+			fmt.Fprintf(&b, " %s", mode)
+		}
+		b.WriteString("\n")
+	}
+
+	if f.IsBinary && len(f.Hunks) == 0 {
+		oldLabel, newLabel := quotePrefixed("a/", oldPath), quotePrefixed("b/", newPath)
+		if f.Status == FileStatusNew {
+			oldLabel = "/dev/null"
+		}
+		if f.Status == FileStatusDeleted {
+			newLabel = "/dev/null"
+		}
+		fmt.Fprintf(&b, "Binary files %s and %s differ\n", oldLabel, newLabel)
+	}
+
+	if len(f.Hunks) > 0 {
+		if f.Status == FileStatusNew {
+			b.WriteString("--- /dev/null\n")
+		} else {
+			fmt.Fprintf(&b, "--- %s\n", filenameMarkerLabel("a/", oldPath))
+		}
+		if f.Status == FileStatusDeleted {
+			b.WriteString("+++ /dev/null\n")
+		} else {
+			fmt.Fprintf(&b, "+++ %s\n", filenameMarkerLabel("b/", newPath))
+		}
+		for _, hunk := range f.Hunks {
+			b.WriteString(hunk.String())
+		}
+	}
+	return b.String()
+}
+
+// displayPaths falls back on the rename names and on the other side's path,
+// so that files with only one of the paths set still render.
+func (f *FileDiff) displayPaths() (oldPath, newPath string) {
+	oldPath, newPath = f.OldPath, f.NewPath
+	if oldPath == "" {
+		oldPath = f.OldName
+	}
+	if newPath == "" {
+		newPath = f.NewName
+	}
+	if oldPath == "" {
+		oldPath = newPath
+	}
+	if newPath == "" {
+		newPath = oldPath
+	}
+	return oldPath, newPath
+}
+
+// headerLine returns the "diff --git" line for the file's current paths.
+func (f *FileDiff) headerLine() string {
+	oldPath, newPath := f.displayPaths()
+	return fmt.Sprintf("diff --git %s %s\n", quotePrefixed("a/", oldPath), quotePrefixed("b/", newPath))
+}
+
+// filenameMarkerLabel returns the label of a ---/+++ line. Like git, it ends
+// unquoted labels that contain a space with a tab.
+func filenameMarkerLabel(prefix, path string) string {
+	label := quotePrefixed(prefix, path)
+	if strings.Contains(label, " ") && !strings.HasPrefix(label, `"`) {
+		label += "\t"
+	}
+	return label
+}
+
+// indexMode returns the mode git appends to the index line, which it only
+// does when the mode did not change.
+func (f *FileDiff) indexMode() FileMode {
+	if f.Status == FileStatusNew || f.Status == FileStatusDeleted {
+		return ""
+	}
+	if f.OldMode == "" || f.OldMode == f.NewMode {
+		return f.NewMode
+	}
+	return ""
+}
+
+func (h *Hunk) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "@@ -%s +%s @@", formatHunkRange(h.OldStart, h.OldLineCount), formatHunkRange(h.NewStart, h.NewLineCount))
+	if h.Section != "" {
+		b.WriteString(" " + h.Section)
+	}
+	b.WriteString("\n")
+	for _, line := range h.Lines {
+		b.WriteString(line.String())
+	}
+	return b.String()
+}
+
+func formatHunkRange(start, count int) string {
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return strconv.Itoa(start) + "," + strconv.Itoa(count)
+}
+
+func (l *HunkLine) String() string {
+	var prefix string
+	switch l.Type {
+	case HunkLineAdded:
+		prefix = "+"
+	case HunkLineDeleted:
+		prefix = "-"
+	default:
+		prefix = " "
+	}
+	if !strings.HasSuffix(l.Content, "\n") {
+		return prefix + l.Content + "\n" + noNewlineMarker
+	}
+	return prefix + l.Content
+}
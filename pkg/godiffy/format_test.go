@@ -0,0 +1,60 @@
+package godiffy
+
+import "testing"
+
+func TestFormatRoundTrip(t *testing.T) {
+	input := `diff --git a/foo.txt b/foo.txt
+index abc123..def456 100644
+--- a/foo.txt
++++ b/foo.txt
+@@ -1,3 +1,4 @@ section
+ line1
+-line2
++new2
++new3
+ line3
+diff --git a/new.txt b/new.txt
+new file mode 100644
+index 0000000..5626abf
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++one
+\ No newline at end of file
+diff --git a/old.txt b/old.txt
+deleted file mode 100755
+index 5626abf..0000000
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-one
+diff --git a/run.sh b/run.sh
+old mode 100644
+new mode 100755
+`
+
+	diff, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if got := diff.String(); got != input {
+		t.Errorf("String() =\n%s\nwant\n%s", got, input)
+	}
+}
+
+func TestFormatRename(t *testing.T) {
+	file := &FileDiff{
+		Status:          FileStatusRenamed,
+		OldName:         "old.txt",
+		NewName:         "new.txt",
+		SimilarityIndex: "100%",
+	}
+	want := `diff --git a/old.txt b/new.txt
+similarity index 100%
+rename from old.txt
+rename to new.txt
+`
+	if got := file.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
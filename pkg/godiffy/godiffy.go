@@ -6,19 +6,43 @@ import (
 	"strings"
 )
 
+func DefaultParseOptions() *ParseOptions {
+	return &ParseOptions{}
+}
+
 func Parse(input string) (*Diff, error) {
+	return ParseWithOptions(input, nil)
+}
+
+// ParseWithOptions parses a diff whose paths may carry other prefixes than
+// a/ and b/. A nil opts detects the prefixes of every file.
+func ParseWithOptions(input string, opts *ParseOptions) (*Diff, error) {
+	if opts == nil {
+		opts = DefaultParseOptions()
+	}
 	resultDiff := &Diff{}
 	var currentFile *FileDiff
 	var currentHunk *Hunk
 	isHeader := true
 	isHunk := false
+	prefixes := opts.prefixes()
 
 	for line := range strings.Lines(input) {
+		if strings.HasPrefix(line, "Submodule ") { // Submodule sub 46841a5..9ffcedc:
+			var err error
+			currentFile, err = parseSubmoduleLine(resultDiff, currentFile, line)
+			if err != nil {
+				return nil, err
+			}
+			isHeader = false
+			isHunk = false
+			continue
+		}
 		if currentFile == nil && !strings.HasPrefix(line, "diff --git") {
 			continue
 		}
-		if strings.HasPrefix(line, "di") { // diff --git a/foo.txt b/foo.txt
-			currentFile = parseNewFileDiff(resultDiff, line)
+		if strings.HasPrefix(line, "diff ") { // diff --git a/foo.txt b/foo.txt
+			currentFile, prefixes = parseNewFileDiff(resultDiff, line, opts, prefixes)
 			isHeader = true
 			isHunk = false
 			continue
@@ -42,12 +66,12 @@ func Parse(input string) (*Diff, error) {
 					return nil, err
 				}
 			case strings.HasPrefix(line, "--- "): // --- a/foo.txt
-				err := parseOldFilenameMarker(currentFile, line)
+				err := parseOldFilenameMarker(currentFile, line, prefixes[0], opts.detect())
 				if err != nil {
 					return nil, err
 				}
 			case strings.HasPrefix(line, "+++ "): // +++ b/foo.txt
-				err := parseNewFilenameMarker(currentFile, line)
+				err := parseNewFilenameMarker(currentFile, line, prefixes[1], opts.detect())
 				if err != nil {
 					return nil, err
 				}
@@ -73,6 +97,30 @@ func Parse(input string) (*Diff, error) {
 						return nil, err
 					}
 				}
+			case strings.HasPrefix(line, "di"): // dissimilarity index 97%
+				err := parseDissimilarityIndex(currentFile, line)
+				if err != nil {
+					return nil, err
+				}
+			case strings.HasPrefix(line, "s"): // similarity index 90%
+				err := parseSimilarityIndex(currentFile, line)
+				if err != nil {
+					return nil, err
+				}
+			case strings.HasPrefix(line, "c"): // copy from old_name.txt / copy to new_name.txt
+				if strings.HasPrefix(line, "copy f") {
+					err := parseCopyFrom(currentFile, line)
+					if err != nil {
+						return nil, err
+					}
+				} else {
+					err := parseCopyTo(currentFile, line)
+					if err != nil {
+						return nil, err
+					}
+				}
+			case strings.HasPrefix(line, "B"): // Binary files a/foo.bin and b/foo.bin differ
+				currentFile.IsBinary = true
 			case strings.HasPrefix(line, "o"): // old mode 100755
 				err := parseOldMode(currentFile, line)
 				if err != nil {
@@ -88,6 +136,13 @@ func Parse(input string) (*Diff, error) {
 			}
 		}
 
+		if !isHeader && !isHunk { //   > commit subject
+			err := parseSubmoduleLog(currentFile, line)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		if isHunk {
 			switch {
 			case strings.HasPrefix(line, "+"): // +added line
@@ -105,21 +160,190 @@ func Parse(input string) (*Diff, error) {
 				if err != nil {
 					return nil, err
 				}
+			case strings.HasPrefix(line, "\\"): // \ No newline at end of file
+				err := parseNoNewlineMarker(currentHunk, line)
+				if err != nil {
+					return nil, err
+				}
 			default:
 				return nil, fmt.Errorf("failed to parse line: %s", line)
 			}
 		}
 	}
+	for _, file := range resultDiff.Files {
+		if err := parseSubproject(file); err != nil {
+			return nil, err
+		}
+	}
 	return resultDiff, nil
 }
 
-func parseNewFileDiff(resultDiff *Diff, line string) (currentFile *FileDiff) {
-	currentFile = &FileDiff{
+func parseNewFileDiff(resultDiff *Diff, line string, opts *ParseOptions, last [2]string) (*FileDiff, [2]string) {
+	currentFile := &FileDiff{
 		Header: line,
+		Status: FileStatusModified,
+	}
+	oldRaw, newRaw, prefixes := parseHeaderPaths(line, opts, last)
+	if oldRaw != "" || newRaw != "" {
+		currentFile.OldRawPath, currentFile.NewRawPath = oldRaw, newRaw
+		currentFile.OldPath = strings.TrimPrefix(oldRaw, prefixes[0])
+		currentFile.NewPath = strings.TrimPrefix(newRaw, prefixes[1])
 	}
 	resultDiff.Files = append(resultDiff.Files, currentFile)
-	return currentFile
+	return currentFile, prefixes
+}
+
+// mnemonicPrefixes are the prefix pairs git uses by default and with
+// diff.mnemonicPrefix, tried in this order when detecting prefixes.
+var mnemonicPrefixes = [][2]string{
+	{"a/", "b/"},
+	{"i/", "w/"},
+	{"c/", "w/"},
+	{"c/", "i/"},
+	{"o/", "w/"},
+	{"1/", "2/"},
+}
+
+// parseHeaderPaths reads the paths, prefixes included, from "diff --git
+// a/foo b/foo" and returns the prefixes they use. Files without ---/+++
+// lines (empty files, mode changes) only name themselves here. Quoted sides
+// end at their closing quote; unquoted paths with spaces are only certain
+// when both sides name the same file. Headers that name no known prefixes
+// keep the last ones.
+func parseHeaderPaths(line string, opts *ParseOptions, last [2]string) (oldRaw, newRaw string, prefixes [2]string) {
+	rest := strings.TrimSuffix(strings.TrimPrefix(line, "diff --git "), "\n")
+	candidates := [][2]string{opts.prefixes()}
+	if opts.detect() {
+		candidates = append(append([][2]string(nil), mnemonicPrefixes...), last)
+	}
+	hasPrefixes := func(left, right string, p [2]string) bool {
+		return strings.HasPrefix(left, p[0]) && strings.HasPrefix(right, p[1])
+	}
+
+	if strings.HasPrefix(rest, `"`) || strings.HasSuffix(rest, `"`) {
+		left, right, ok := splitQuotedHeader(rest)
+		if !ok {
+			return "", "", last
+		}
+		for _, p := range candidates {
+			if hasPrefixes(left, right, p) {
+				return left, right, p
+			}
+		}
+		if p, ok := detectPrefixes(left, right); ok && opts.detect() {
+			return left, right, p
+		}
+		return "", "", last
+	}
+
+	if n := len(rest); n%2 == 1 && rest[n/2] == ' ' { // both sides may name the same file
+		left, right := rest[:n/2], rest[n/2+1:]
+		known := false
+		for _, p := range candidates {
+			if hasPrefixes(left, right, p) && left[len(p[0]):] == right[len(p[1]):] {
+				return left, right, p
+			}
+			known = known || (p != [2]string{} && hasPrefixes(left, right, p))
+		}
+		// Sides with known prefixes but different names are a rename,
+		// whose common tail says nothing about the prefixes.
+		if p, ok := detectPrefixes(left, right); ok && opts.detect() && !known {
+			return left, right, p
+		}
+	}
+	for _, p := range candidates {
+		if p[1] == "" || !strings.HasPrefix(rest, p[0]) {
+			continue
+		}
+		if left, right, ok := strings.Cut(rest, " "+p[1]); ok {
+			return left, p[1] + right, p
+		}
+	}
+	return "", "", last
+}
+
+// splitQuotedHeader splits a header where at least one side is quoted,
+// like diff --git a/mv me "b/moved \303\266".
+func splitQuotedHeader(rest string) (left, right string, ok bool) {
+	var err error
+	if strings.HasPrefix(rest, `"`) {
+		left, rest, err = cutQuoted(rest)
+		if err != nil || !strings.HasPrefix(rest, " ") {
+			return "", "", false
+		}
+		right, err = unquotePath(rest[1:])
+		return left, right, err == nil
+	}
+	// The old side is not quoted, so it ends at the last quote that starts
+	// a valid quoted new side.
+	for i := strings.LastIndex(rest, ` "`); i != -1; i = strings.LastIndex(rest[:i], ` "`) {
+		if right, err = unquotePath(rest[i+1:]); err == nil {
+			return rest[:i], right, true
+		}
+	}
+	return "", "", false
+}
+
+// detectPrefixes finds the prefixes of two header sides that name the same
+// file, like "old/foo" and "new/foo": what is left in front of the longest
+// common tail that starts a path component. Equal sides have no prefixes.
+func detectPrefixes(left, right string) ([2]string, bool) {
+	n := 0
+	for n < len(left) && n < len(right) && left[len(left)-1-n] == right[len(right)-1-n] {
+		n++
+	}
+	for ; n > 0; n-- {
+		src, dst := left[:len(left)-n], right[:len(right)-n]
+		if (src == "" && dst == "") || (strings.HasSuffix(src, "/") && strings.HasSuffix(dst, "/")) {
+			return [2]string{src, dst}, true
+		}
+	}
+	return [2]string{}, false
+}
+
+// parsePathValue reads the path of a "rename from" or "copy to" line, which
+// git quotes like every other path. Unlike an unquoted header with spaces,
+// these lines are never ambiguous, so the callers also take them as
+// OldPath and NewPath.
+func parsePathValue(line, prefix string) (string, error) {
+	value := strings.TrimSuffix(strings.TrimPrefix(line, prefix), "\n")
+	path, err := unquotePath(value)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", fmt.Errorf("missing path")
+	}
+	return path, nil
+}
+
+// parseFilenameMarkerPath reads the label of a ---/+++ line. Git ends
+// unquoted labels that contain a space with a tab, and other tools put a
+// timestamp after the tab.
+func parseFilenameMarkerPath(line string) (string, error) {
+	label := strings.TrimSuffix(line[4:], "\n")
+	if strings.HasPrefix(label, `"`) {
+		path, _, err := cutQuoted(label)
+		return path, err
+	}
+	label, _, _ = strings.Cut(label, "\t")
+	return strings.TrimSuffix(label, "\r"), nil
+}
 
+// stripPrefix removes the prefix from a ---/+++ label. When the prefixes
+// were detected and the label does not carry them, everything up to the
+// first slash is taken as the prefix.
+func stripPrefix(label, prefix string, detect bool) (string, error) {
+	if path, ok := strings.CutPrefix(label, prefix); ok {
+		return path, nil
+	}
+	if !detect {
+		return "", fmt.Errorf("missing prefix %s", prefix)
+	}
+	if _, path, ok := strings.Cut(label, "/"); ok {
+		return path, nil
+	}
+	return label, nil
 }
 
 func parseHunk(currentFile *FileDiff, line string) (*Hunk, error) {
@@ -129,47 +353,66 @@ func parseHunk(currentFile *FileDiff, line string) (*Hunk, error) {
 	if len(parts) < 4 {
 		return nil, fmt.Errorf("invalid hunk format: %s", line)
 	}
-	oldParts := strings.Split(parts[1], ",") // ["-1","3"]
-	newParts := strings.Split(parts[2], ",")
-	if len(oldParts) != 2 && len(newParts) != 2 {
-		return nil, fmt.Errorf("invalid hunk format: %s", line)
-	}
-	hunk.OldStart, err = strconv.Atoi(strings.TrimPrefix(oldParts[0], "-")) // -1
+	oldStart, oldCount, hasOldCount := strings.Cut(strings.TrimPrefix(parts[1], "-"), ",") // "1","3"
+	newStart, newCount, hasNewCount := strings.Cut(strings.TrimPrefix(parts[2], "+"), ",")
+	hunk.OldStart, err = strconv.Atoi(oldStart)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse old start line %s: %w", line, err)
 	}
-	hunk.NewStart, err = strconv.Atoi(strings.TrimPrefix(newParts[0], "+")) // +1
+	hunk.NewStart, err = strconv.Atoi(newStart)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse new start line %s: %w", line, err)
 	}
-	hunk.OldLineCount, err = strconv.Atoi(oldParts[1]) // 3
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse old lines %s: %w", line, err)
+	hunk.OldLineCount, hunk.NewLineCount = 1, 1 // git omits a count of one: @@ -1 +1 @@
+	if hasOldCount {
+		hunk.OldLineCount, err = strconv.Atoi(oldCount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse old lines %s: %w", line, err)
+		}
 	}
-	hunk.NewLineCount, err = strconv.Atoi(newParts[1]) // 4
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse new lines %s: %w", line, err)
+	if hasNewCount {
+		hunk.NewLineCount, err = strconv.Atoi(newCount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse new lines %s: %w", line, err)
+		}
+	}
+	if _, section, ok := strings.Cut(strings.TrimPrefix(line, "@@"), "@@"); ok {
+		hunk.Section = strings.TrimSpace(section)
 	}
 
 	currentFile.Hunks = append(currentFile.Hunks, hunk)
 	return hunk, nil
 }
 
-func parseOldFilenameMarker(currentFile *FileDiff, line string) error {
-	parts := strings.SplitN(line, "/", 2)
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid filename format: %s", line)
+func parseOldFilenameMarker(currentFile *FileDiff, line, prefix string, detect bool) error {
+	label, err := parseFilenameMarkerPath(line)
+	if err != nil {
+		return fmt.Errorf("invalid filename format: %s: %w", line, err)
+	}
+	if label == "/dev/null" { // new file, the path comes from the header
+		return nil
 	}
-	currentFile.OldPath = strings.TrimSpace(parts[1])
+	path, err := stripPrefix(label, prefix, detect)
+	if err != nil {
+		return fmt.Errorf("invalid filename format: %s: %w", line, err)
+	}
+	currentFile.OldRawPath, currentFile.OldPath = label, path
 	return nil
 }
 
-func parseNewFilenameMarker(currentFile *FileDiff, line string) error {
-	parts := strings.SplitN(line, "/", 2)
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid filename format: %s", line)
+func parseNewFilenameMarker(currentFile *FileDiff, line, prefix string, detect bool) error {
+	label, err := parseFilenameMarkerPath(line)
+	if err != nil {
+		return fmt.Errorf("invalid filename format: %s: %w", line, err)
 	}
-	currentFile.NewPath = strings.TrimSpace(parts[1])
+	if label == "/dev/null" { // deleted file, the path comes from the header
+		return nil
+	}
+	path, err := stripPrefix(label, prefix, detect)
+	if err != nil {
+		return fmt.Errorf("invalid filename format: %s: %w", line, err)
+	}
+	currentFile.NewRawPath, currentFile.NewPath = label, path
 	return nil
 }
 
@@ -209,6 +452,15 @@ func parseContextLine(hunk *Hunk, line string) error {
 	return nil
 }
 
+func parseNoNewlineMarker(hunk *Hunk, line string) error {
+	if hunk == nil || len(hunk.Lines) == 0 {
+		return fmt.Errorf("failed to parse no newline marker: no preceding line")
+	}
+	last := hunk.Lines[len(hunk.Lines)-1]
+	last.Content = strings.TrimSuffix(last.Content, "\n")
+	return nil
+}
+
 func parseMetadata(currentFile *FileDiff, line string) error {
 	parts := strings.Fields(line) // ["index","abc123..def456","100644"]
 	if len(parts) < 2 {
@@ -218,9 +470,20 @@ func parseMetadata(currentFile *FileDiff, line string) error {
 	if len(hashes) != 2 {
 		return fmt.Errorf("invalid hash format: %s", line)
 	}
-	currentFile.OldHash, currentFile.NewHash = strings.TrimSpace(hashes[0]), strings.TrimSpace(hashes[1])
+	oldHash, err := ParseObjectID(hashes[0])
+	if err != nil {
+		return err
+	}
+	newHash, err := ParseObjectID(hashes[1])
+	if err != nil {
+		return err
+	}
+	currentFile.OldHash, currentFile.NewHash = oldHash, newHash
 	if len(parts) > 2 {
-		currentFile.NewMode = strings.TrimSpace(parts[2])
+		currentFile.NewMode, err = ParseFileMode(parts[2])
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -230,7 +493,11 @@ func parseNewFileMode(currentFile *FileDiff, line string) error {
 	if len(parts) < 4 {
 		return fmt.Errorf("invalid new file mode format: %s", line)
 	}
-	currentFile.NewMode = strings.TrimSpace(parts[3])
+	mode, err := ParseFileMode(parts[3])
+	if err != nil {
+		return err
+	}
+	currentFile.NewMode = mode
 	currentFile.Status = FileStatusNew
 	return nil
 }
@@ -240,37 +507,83 @@ func parseDeletedFileMode(currentFile *FileDiff, line string) error {
 	if len(parts) < 4 {
 		return fmt.Errorf("invalid deleted file mode format: %s", line)
 	}
-	currentFile.OldMode = strings.TrimSpace(parts[3])
+	mode, err := ParseFileMode(parts[3])
+	if err != nil {
+		return err
+	}
+	currentFile.OldMode = mode
 	currentFile.Status = FileStatusDeleted
 	return nil
 }
 
 func parseRenameFrom(currentFile *FileDiff, line string) error {
-	parts := strings.Fields(line)
-	if len(parts) != 3 {
-		return fmt.Errorf("invalid rename from format: %s", line)
+	path, err := parsePathValue(line, "rename from ")
+	if err != nil {
+		return fmt.Errorf("invalid rename from format: %s: %w", line, err)
 	}
-	currentFile.OldName = strings.TrimSpace(parts[2])
+	currentFile.OldName, currentFile.OldPath = path, path
 	currentFile.Status = FileStatusRenamed
 	return nil
 }
 
 func parseRenameTo(currentFile *FileDiff, line string) error {
-	parts := strings.Fields(line)
-	if len(parts) != 3 {
-		return fmt.Errorf("invalid rename to format: %s", line)
+	path, err := parsePathValue(line, "rename to ")
+	if err != nil {
+		return fmt.Errorf("invalid rename to format: %s: %w", line, err)
 	}
-	currentFile.NewName = strings.TrimSpace(parts[2])
+	currentFile.NewName, currentFile.NewPath = path, path
 	currentFile.Status = FileStatusRenamed
 	return nil
 }
 
+func parseSimilarityIndex(currentFile *FileDiff, line string) error {
+	parts := strings.Fields(line)
+	if len(parts) != 3 || !strings.HasSuffix(parts[2], "%") {
+		return fmt.Errorf("invalid similarity index format: %s", line)
+	}
+	currentFile.SimilarityIndex = parts[2]
+	return nil
+}
+
+func parseDissimilarityIndex(currentFile *FileDiff, line string) error {
+	parts := strings.Fields(line)
+	if len(parts) != 3 || !strings.HasSuffix(parts[2], "%") {
+		return fmt.Errorf("invalid dissimilarity index format: %s", line)
+	}
+	currentFile.DissimilarityIndex = parts[2]
+	return nil
+}
+
+func parseCopyFrom(currentFile *FileDiff, line string) error {
+	path, err := parsePathValue(line, "copy from ")
+	if err != nil {
+		return fmt.Errorf("invalid copy from format: %s: %w", line, err)
+	}
+	currentFile.OldName, currentFile.OldPath = path, path
+	currentFile.Status = FileStatusCopied
+	return nil
+}
+
+func parseCopyTo(currentFile *FileDiff, line string) error {
+	path, err := parsePathValue(line, "copy to ")
+	if err != nil {
+		return fmt.Errorf("invalid copy to format: %s: %w", line, err)
+	}
+	currentFile.NewName, currentFile.NewPath = path, path
+	currentFile.Status = FileStatusCopied
+	return nil
+}
+
 func parseOldMode(currentFile *FileDiff, line string) error {
 	parts := strings.Fields(line)
 	if len(parts) != 3 {
 		return fmt.Errorf("invalid old mode format: %s", line)
 	}
-	currentFile.OldMode = strings.TrimSpace(parts[2])
+	mode, err := ParseFileMode(parts[2])
+	if err != nil {
+		return err
+	}
+	currentFile.OldMode = mode
 	return nil
 }
 
@@ -279,6 +592,27 @@ func parseNewMode(currentFile *FileDiff, line string) error {
 	if len(parts) != 3 {
 		return fmt.Errorf("invalid new mode format: %s", line)
 	}
-	currentFile.NewMode = strings.TrimSpace(parts[2])
+	mode, err := ParseFileMode(parts[2])
+	if err != nil {
+		return err
+	}
+	currentFile.NewMode = mode
 	return nil
 }
+
+// detect reports whether the prefixes are left to detection.
+func (o *ParseOptions) detect() bool {
+	return !o.NoPrefix && o.SrcPrefix == "" && o.DstPrefix == ""
+}
+
+// prefixes returns the declared prefixes, or git's default ones to start
+// detection from.
+func (o *ParseOptions) prefixes() [2]string {
+	switch {
+	case o.NoPrefix:
+		return [2]string{}
+	case o.detect():
+		return [2]string{"a/", "b/"}
+	}
+	return [2]string{o.SrcPrefix, o.DstPrefix}
+}
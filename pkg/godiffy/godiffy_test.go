@@ -1,6 +1,8 @@
 package godiffy
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -109,3 +111,178 @@ index abc123..def456 100644
 		t.Errorf("h2 start/count = %d/%d, want 5/3", h2.NewStart, h2.NewLineCount)
 	}
 }
+
+func TestParseOmittedLineCounts(t *testing.T) {
+	input := `diff --git a/foo.txt b/foo.txt
+index abc123..def456 100644
+--- a/foo.txt
++++ b/foo.txt
+@@ -3 +3,2 @@ func main() {
+-old
++new
++newer
+`
+
+	diff, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	// A file without "new file" or "deleted file" lines is a modification.
+	if diff.Files[0].Status != FileStatusModified {
+		t.Errorf("expected status %d, got %d", FileStatusModified, diff.Files[0].Status)
+	}
+	h := diff.Files[0].Hunks[0]
+	if h.OldStart != 3 || h.OldLineCount != 1 {
+		t.Errorf("h start/count = %d/%d, want 3/1", h.OldStart, h.OldLineCount)
+	}
+	if h.NewStart != 3 || h.NewLineCount != 2 {
+		t.Errorf("h start/count = %d/%d, want 3/2", h.NewStart, h.NewLineCount)
+	}
+	if h.Section != "func main() {" {
+		t.Errorf("expected Section 'func main() {', got '%s'", h.Section)
+	}
+}
+
+func TestParseNewAndDeletedFilePaths(t *testing.T) {
+	input := `diff --git a/added.txt b/added.txt
+new file mode 100644
+index 0000000..5626abf
+--- /dev/null
++++ b/added.txt
+@@ -0,0 +1 @@
++one
+\ No newline at end of file
+diff --git a/removed.txt b/removed.txt
+deleted file mode 100644
+index 5626abf..0000000
+--- a/removed.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-one
+diff --git a/empty.txt b/empty.txt
+new file mode 100644
+index 0000000..e69de29
+`
+
+	diff, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(diff.Files) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(diff.Files))
+	}
+
+	for i, want := range []string{"added.txt", "removed.txt", "empty.txt"} {
+		file := diff.Files[i]
+		if file.OldPath != want || file.NewPath != want {
+			t.Errorf("file %d paths = %q/%q, want %q", i, file.OldPath, file.NewPath, want)
+		}
+	}
+
+	added := diff.Files[0].Hunks[0].Lines[0]
+	if added.Content != "one" {
+		t.Errorf("expected content without newline 'one', got %q", added.Content)
+	}
+}
+
+// From "git diff -B", which shows a file it found rewritten, here after a
+// mode change, as a complete rewrite.
+func TestParseDissimilarityIndex(t *testing.T) {
+	input := `diff --git a/f.txt b/f.txt
+old mode 100644
+new mode 100755
+dissimilarity index 100%
+index 89010aa..814f4a4
+--- a/f.txt
++++ b/f.txt
+@@ -1,20 +1,2 @@
+-line 1 of the original text
+-line 2 of the original text
+-line 3 of the original text
+-line 4 of the original text
+-line 5 of the original text
+-line 6 of the original text
+-line 7 of the original text
+-line 8 of the original text
+-line 9 of the original text
+-line 10 of the original text
+-line 11 of the original text
+-line 12 of the original text
+-line 13 of the original text
+-line 14 of the original text
+-line 15 of the original text
+-line 16 of the original text
+-line 17 of the original text
+-line 18 of the original text
+-line 19 of the original text
+-line 20 of the original text
++one
++two
+`
+
+	diff, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(diff.Files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(diff.Files))
+	}
+	file := diff.Files[0]
+	if file.DissimilarityIndex != "100%" || file.Status != FileStatusModified || file.NewMode != FileModeExecutable {
+		t.Errorf("unexpected file: %+v", file)
+	}
+	if len(file.Hunks) != 1 || len(file.Hunks[0].Lines) != 22 {
+		t.Fatalf("expected one hunk of 22 lines, got %+v", file.Hunks)
+	}
+	if got := diff.String(); got != input {
+		t.Errorf("String() =\n%s\nwant\n%s", got, input)
+	}
+}
+
+// The fixtures are one change from "git diff -M" with the default,
+// mnemonic, --no-prefix and custom --src-prefix/--dst-prefix settings, so
+// they must all parse into the diff of default.diff.
+func TestParsePrefixes(t *testing.T) {
+	want, err := os.ReadFile(filepath.Join("testdata", "prefix", "default.diff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		fixture        string
+		opts           *ParseOptions
+		oldRaw, newRaw string
+	}{
+		{"default.diff", nil, "a/docs/readme", "b/docs/readme"},
+		{"mnemonic.diff", nil, "c/docs/readme", "i/docs/readme"},
+		{"no-prefix.diff", nil, "docs/readme", "docs/readme"},
+		{"no-prefix.diff", &ParseOptions{NoPrefix: true}, "docs/readme", "docs/readme"},
+		{"custom.diff", nil, "old/docs/readme", "new/docs/readme"},
+		{"custom.diff", &ParseOptions{SrcPrefix: "old/", DstPrefix: "new/"}, "old/docs/readme", "new/docs/readme"},
+		{"custom-noslash.diff", &ParseOptions{SrcPrefix: "L:", DstPrefix: "R:"}, "L:docs/readme", "R:docs/readme"},
+	}
+	for _, tt := range tests {
+		input, err := os.ReadFile(filepath.Join("testdata", "prefix", tt.fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		diff, err := ParseWithOptions(string(input), tt.opts)
+		if err != nil {
+			t.Fatalf("%s: ParseWithOptions returned error: %v", tt.fixture, err)
+		}
+		if got := diff.String(); got != string(want) {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.fixture, got, want)
+		}
+		readme := diff.Files[1]
+		if readme.OldRawPath != tt.oldRaw || readme.NewRawPath != tt.newRaw {
+			t.Errorf("%s: raw paths %q, %q, want %q, %q", tt.fixture, readme.OldRawPath, readme.NewRawPath, tt.oldRaw, tt.newRaw)
+		}
+	}
+}
+
+func TestParseWrongPrefix(t *testing.T) {
+	input := "diff --git a/x b/x\nindex 1..2 100644\n--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n+b\n"
+	if _, err := ParseWithOptions(input, &ParseOptions{SrcPrefix: "i/", DstPrefix: "w/"}); err == nil {
+		t.Errorf("expected an error for labels without the declared prefix")
+	}
+}
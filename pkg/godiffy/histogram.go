@@ -0,0 +1,166 @@
+package godiffy
+
+// histogramMaxChain is the number of occurrences above which a line is too
+// common to anchor the diff, like git's max_chain_length.
+const histogramMaxChain = 64
+
+type histogramRecord struct {
+	ptr int // first occurrence, 1-based
+	cnt int // number of occurrences
+}
+
+// histogramRegion is a run of common lines, 1-based and inclusive. A zero
+// region means none was found.
+type histogramRegion struct {
+	begin1, end1 int
+	begin2, end2 int
+}
+
+type histogram struct {
+	a, b               []int
+	changed1, changed2 []bool
+}
+
+// histogramDiff marks the changed lines of a and b with the histogram
+// algorithm, an extension of patience that anchors the diff on the least
+// frequent common lines instead of requiring unique ones. Like git it works
+// with 1-based line numbers.
+func histogramDiff(a, b []int) (changed1, changed2 []bool) {
+	h := &histogram{
+		a:        a,
+		b:        b,
+		changed1: make([]bool, len(a)),
+		changed2: make([]bool, len(b)),
+	}
+	h.diff(1, len(a), 1, len(b))
+	return h.changed1, h.changed2
+}
+
+func (h *histogram) diff(line1, count1, line2, count2 int) {
+	for {
+		if count1 <= 0 && count2 <= 0 {
+			return
+		}
+		if count1 == 0 {
+			markChanged(h.changed2, line2-1, count2)
+			return
+		}
+		if count2 == 0 {
+			markChanged(h.changed1, line1-1, count1)
+			return
+		}
+
+		lcs, fallBack := h.findLCS(line1, count1, line2, count2)
+		if fallBack {
+			changed1, changed2 := myersDiff(h.a[line1-1:line1-1+count1], h.b[line2-1:line2-1+count2])
+			copy(h.changed1[line1-1:], changed1)
+			copy(h.changed2[line2-1:], changed2)
+			return
+		}
+		if lcs.begin1 == 0 && lcs.begin2 == 0 {
+			markChanged(h.changed1, line1-1, count1)
+			markChanged(h.changed2, line2-1, count2)
+			return
+		}
+
+		h.diff(line1, lcs.begin1-line1, line2, lcs.begin2-line2)
+		count1 = line1 + count1 - 1 - lcs.end1
+		line1 = lcs.end1 + 1
+		count2 = line2 + count2 - 1 - lcs.end2
+		line2 = lcs.end2 + 1
+	}
+}
+
+// histogramIndex counts the occurrences of each line in the first range and
+// chains the occurrences of equal lines together.
+type histogramIndex struct {
+	records   map[int]*histogramRecord
+	lineMap   []*histogramRecord
+	nextPtrs  []int
+	ptrShift  int
+	cnt       int
+	hasCommon bool
+}
+
+func (h *histogram) findLCS(line1, count1, line2, count2 int) (lcs histogramRegion, fallBack bool) {
+	index := &histogramIndex{
+		records:  make(map[int]*histogramRecord),
+		lineMap:  make([]*histogramRecord, count1),
+		nextPtrs: make([]int, count1),
+		ptrShift: line1,
+		cnt:      histogramMaxChain + 1,
+	}
+
+	for ptr := line1 + count1 - 1; ptr >= line1; ptr-- {
+		id := h.a[ptr-1]
+		rec, ok := index.records[id]
+		if ok {
+			index.nextPtrs[ptr-line1] = rec.ptr
+			rec.ptr = ptr
+			rec.cnt++
+		} else {
+			rec = &histogramRecord{ptr: ptr, cnt: 1}
+			index.records[id] = rec
+		}
+		index.lineMap[ptr-line1] = rec
+	}
+
+	for bPtr := line2; bPtr <= line2+count2-1; {
+		bPtr = h.tryLCS(index, &lcs, bPtr, line1, count1, line2, count2)
+	}
+
+	return lcs, index.hasCommon && histogramMaxChain < index.cnt
+}
+
+func (h *histogram) tryLCS(index *histogramIndex, lcs *histogramRegion, bPtr, line1, count1, line2, count2 int) int {
+	bNext := bPtr + 1
+	end1, end2 := line1+count1-1, line2+count2-1
+
+	rec, ok := index.records[h.b[bPtr-1]]
+	if !ok {
+		return bNext
+	}
+	index.hasCommon = true
+	if rec.cnt > index.cnt {
+		return bNext
+	}
+
+	for as := rec.ptr; ; {
+		np := index.nextPtrs[as-index.ptrShift]
+		bs, ae, be, rc := bPtr, as, bPtr, rec.cnt
+
+		for line1 < as && line2 < bs && h.a[as-2] == h.b[bs-2] {
+			as--
+			bs--
+			if rc > 1 {
+				rc = min(rc, index.lineMap[as-index.ptrShift].cnt)
+			}
+		}
+		for ae < end1 && be < end2 && h.a[ae] == h.b[be] {
+			ae++
+			be++
+			if rc > 1 {
+				rc = min(rc, index.lineMap[ae-index.ptrShift].cnt)
+			}
+		}
+
+		if bNext <= be {
+			bNext = be + 1
+		}
+		if lcs.end1-lcs.begin1 < ae-as || rc < index.cnt {
+			*lcs = histogramRegion{begin1: as, end1: ae, begin2: bs, end2: be}
+			index.cnt = rc
+		}
+
+		if np == 0 {
+			return bNext
+		}
+		for np <= ae {
+			np = index.nextPtrs[np-index.ptrShift]
+			if np == 0 {
+				return bNext
+			}
+		}
+		as = np
+	}
+}
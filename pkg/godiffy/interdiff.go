@@ -0,0 +1,305 @@
+package godiffy
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// interdiffMinSimilarity is the share of common lines, in percent, two
+// versions of a hunk need to count as the same hunk modified.
+const interdiffMinSimilarity = 50
+
+// Interdiff compares two versions of a patch and reports which files and
+// hunks were added, dropped or modified in v2. Hunks are compared by their
+// lines and not by their position, so the versions may be based on
+// different trees.
+func Interdiff(v1, v2 *Diff) *InterdiffResult {
+	result := &InterdiffResult{}
+	matched := make(map[*FileDiff]bool)
+	// A path can have two entries, like the deletion and creation that
+	// replace a file with a link.
+	byPath := make(map[string][]*FileDiff)
+	for _, file := range v1.Files {
+		_, newPath := file.displayPaths()
+		byPath[newPath] = append(byPath[newPath], file)
+	}
+	for _, f2 := range v2.Files {
+		if f1 := interdiffMatch(byPath, matched, f2); f1 != nil {
+			matched[f1] = true
+			result.Files = append(result.Files, interdiffFile(f1, f2))
+			continue
+		}
+		file := &FileInterdiff{Status: InterdiffAdded, V2: f2}
+		for _, hunk := range f2.Hunks {
+			file.Hunks = append(file.Hunks, addedHunk(hunk))
+		}
+		result.Files = append(result.Files, file)
+	}
+	for _, f1 := range v1.Files {
+		if matched[f1] {
+			continue
+		}
+		file := &FileInterdiff{Status: InterdiffDropped, V1: f1}
+		for _, hunk := range f1.Hunks {
+			file.Hunks = append(file.Hunks, droppedHunk(hunk))
+		}
+		result.Files = append(result.Files, file)
+	}
+
+	slices.SortStableFunc(result.Files, func(a, b *FileInterdiff) int {
+		return comparePaths(a.path(), b.path())
+	})
+	return result
+}
+
+// interdiffMatch returns the v1 entry f2 is a version of: the first one
+// not matched yet at its new path, or else at its old path, preferring one
+// with the same status.
+func interdiffMatch(byPath map[string][]*FileDiff, matched map[*FileDiff]bool, f2 *FileDiff) *FileDiff {
+	oldPath, newPath := f2.displayPaths()
+	var first *FileDiff
+	for _, f1 := range slices.Concat(byPath[newPath], byPath[oldPath]) {
+		if matched[f1] {
+			continue
+		}
+		if f1.Status == f2.Status {
+			return f1
+		}
+		if first == nil {
+			first = f1
+		}
+	}
+	return first
+}
+
+func interdiffFile(f1, f2 *FileDiff) *FileInterdiff {
+	file := &FileInterdiff{Status: InterdiffUnchanged, V1: f1, V2: f2}
+	bodies1, bodies2 := hunkBodies(f1.Hunks), hunkBodies(f2.Hunks)
+
+	// Identical hunks pair up first, then the most similar ones.
+	pair1, pair2 := make([]int, len(f1.Hunks)), make([]int, len(f2.Hunks))
+	for i := range pair1 {
+		pair1[i] = -1
+	}
+	for j := range pair2 {
+		pair2[j] = -1
+	}
+	for j := range f2.Hunks {
+		for i := range f1.Hunks {
+			if pair1[i] == -1 && slices.Equal(bodies1[i], bodies2[j]) {
+				pair1[i], pair2[j] = j, i
+				break
+			}
+		}
+	}
+	var candidates []renameCandidate
+	for i := range f1.Hunks {
+		for j := range f2.Hunks {
+			if pair1[i] != -1 || pair2[j] != -1 {
+				continue
+			}
+			if score := lineSimilarity(bodies1[i], bodies2[j]); score >= interdiffMinSimilarity {
+				candidates = append(candidates, renameCandidate{src: i, dst: j, score: score})
+			}
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b renameCandidate) int {
+		return cmp.Compare(b.score, a.score)
+	})
+	for _, c := range candidates {
+		if pair1[c.src] == -1 && pair2[c.dst] == -1 {
+			pair1[c.src], pair2[c.dst] = c.dst, c.src
+		}
+	}
+
+	// Hunks come in the order of v2, with dropped ones before the first
+	// v2 hunk paired with a later v1 hunk.
+	next := 0
+	dropUntil := func(end int) {
+		for ; next < end; next++ {
+			if pair1[next] == -1 {
+				file.Hunks = append(file.Hunks, droppedHunk(f1.Hunks[next]))
+			}
+		}
+	}
+	for j, hunk := range f2.Hunks {
+		i := pair2[j]
+		if i == -1 {
+			file.Hunks = append(file.Hunks, addedHunk(hunk))
+			continue
+		}
+		dropUntil(i)
+		h := &HunkInterdiff{Status: InterdiffUnchanged, V1: f1.Hunks[i], V2: hunk}
+		if !slices.Equal(bodies1[i], bodies2[j]) {
+			h.Status = InterdiffModified
+			h.Lines = diffBodies(bodies1[i], bodies2[j])
+		}
+		file.Hunks = append(file.Hunks, h)
+	}
+	dropUntil(len(f1.Hunks))
+
+	for _, h := range file.Hunks {
+		if h.Status != InterdiffUnchanged {
+			file.Status = InterdiffModified
+		}
+	}
+	oldPath1, newPath1 := f1.displayPaths()
+	oldPath2, newPath2 := f2.displayPaths()
+	if oldPath1 != oldPath2 || newPath1 != newPath2 {
+		file.Status = InterdiffModified
+	}
+	if f1.Status != f2.Status || f1.OldMode != f2.OldMode || f1.NewMode != f2.NewMode || f1.IsBinary != f2.IsBinary {
+		file.Status = InterdiffModified
+	}
+	if f1.IsBinary && len(f1.Hunks) == 0 && f1.NewHash != f2.NewHash {
+		file.Status = InterdiffModified
+	}
+	return file
+}
+
+func addedHunk(hunk *Hunk) *HunkInterdiff {
+	return &HunkInterdiff{Status: InterdiffAdded, V2: hunk, Lines: diffBodies(nil, hunkBodies([]*Hunk{hunk})[0])}
+}
+
+func droppedHunk(hunk *Hunk) *HunkInterdiff {
+	return &HunkInterdiff{Status: InterdiffDropped, V1: hunk, Lines: diffBodies(hunkBodies([]*Hunk{hunk})[0], nil)}
+}
+
+// hunkBodies renders the lines of each hunk, without the header, the way
+// they appear in a patch.
+func hunkBodies(hunks []*Hunk) [][]string {
+	bodies := make([][]string, len(hunks))
+	for i, hunk := range hunks {
+		var b strings.Builder
+		for _, line := range hunk.Lines {
+			b.WriteString(line.String())
+		}
+		bodies[i] = splitLines(b.String())
+	}
+	return bodies
+}
+
+// lineSimilarity returns the share of lines two texts have in common, in
+// percent of their combined length.
+func lineSimilarity(a, b []string) int {
+	if len(a)+len(b) == 0 {
+		return 100
+	}
+	ids1, ids2 := classifyLines(a, b)
+	changed1, _ := myersDiff(ids1, ids2)
+	common := 0
+	for _, changed := range changed1 {
+		if !changed {
+			common++
+		}
+	}
+	return 2 * common * 100 / (len(a) + len(b))
+}
+
+// diffBodies diffs two hunk bodies with all of their lines as context.
+func diffBodies(a, b []string) []*HunkLine {
+	oldText, newText := strings.Join(a, ""), strings.Join(b, "")
+	computed, err := Compute(oldText, newText, &DiffOptions{Context: max(len(a), len(b))})
+	if err != nil || len(computed.Hunks) == 0 {
+		return nil
+	}
+	return computed.Hunks[0].Lines
+}
+
+func (f *FileInterdiff) path() string {
+	file := f.V2
+	if file == nil {
+		file = f.V1
+	}
+	_, newPath := file.displayPaths()
+	return newPath
+}
+
+// String renders the changes between the two versions the way
+// "git range-diff" shows a modified commit: every line of a patch keeps its
+// own prefix and gets a second one in front that tells whether v2 added,
+// dropped or kept it. Line numbers are left out of the hunk headers, and
+// unchanged files and hunks are skipped.
+func (r *InterdiffResult) String() string {
+	var b strings.Builder
+	for _, file := range r.Files {
+		if file.Status == InterdiffUnchanged {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		path := file.path()
+		var header1, header2 string
+		if file.V1 != nil {
+			header1 = interdiffFileHeader(file.V1)
+		}
+		if file.V2 != nil {
+			header2 = interdiffFileHeader(file.V2)
+		}
+		writeChanged(&b, header1, header2)
+
+		for _, hunk := range file.Hunks {
+			if hunk.Status == InterdiffUnchanged {
+				continue
+			}
+			fmt.Fprintf(&b, "@@ %s\n", quotePath(path))
+			var section1, section2 string
+			if hunk.V1 != nil {
+				section1 = interdiffHunkHeader(hunk.V1)
+			}
+			if hunk.V2 != nil {
+				section2 = interdiffHunkHeader(hunk.V2)
+			}
+			writeChanged(&b, section1, section2)
+			for _, line := range hunk.Lines {
+				b.WriteString(line.String())
+			}
+		}
+	}
+	return b.String()
+}
+
+// writeChanged writes a line of v1 and its counterpart in v2, as one
+// unchanged line if they are the same. An empty line is missing from that
+// version.
+func writeChanged(b *strings.Builder, line1, line2 string) {
+	switch {
+	case line1 == line2:
+		b.WriteString(" " + line1 + "\n")
+	case line2 == "":
+		b.WriteString("-" + line1 + "\n")
+	case line1 == "":
+		b.WriteString("+" + line2 + "\n")
+	default:
+		b.WriteString("-" + line1 + "\n+" + line2 + "\n")
+	}
+}
+
+func interdiffFileHeader(f *FileDiff) string {
+	oldPath, newPath := f.displayPaths()
+	var header string
+	switch f.Status {
+	case FileStatusRenamed, FileStatusCopied:
+		header = quotePath(oldPath) + " => " + quotePath(newPath)
+	case FileStatusNew:
+		header = quotePath(newPath) + " (new)"
+	case FileStatusDeleted:
+		header = quotePath(oldPath) + " (deleted)"
+	default:
+		header = quotePath(newPath)
+	}
+	if f.OldMode != "" && f.NewMode != "" && f.OldMode != f.NewMode {
+		header += fmt.Sprintf(" (mode change %s => %s)", f.OldMode, f.NewMode)
+	}
+	return " ## " + header + " ##"
+}
+
+func interdiffHunkHeader(h *Hunk) string {
+	if h.Section == "" {
+		return " @@"
+	}
+	return " @@ " + h.Section
+}
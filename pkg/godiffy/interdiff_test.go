@@ -0,0 +1,122 @@
+package godiffy
+
+import "testing"
+
+func interdiffTestFile(t *testing.T, path, oldText, newText string) *FileDiff {
+	t.Helper()
+	file, err := Compute(oldText, newText, &DiffOptions{Context: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.OldPath, file.NewPath = path, path
+	return file
+}
+
+func TestInterdiff(t *testing.T) {
+	base := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n"
+	// v2 is based on a tree with two more lines at the top.
+	rebased := "0\n0\n" + base
+	v1 := &Diff{Files: []*FileDiff{
+		interdiffTestFile(t, "a.txt", base, "1\nTWO\n3\n4\n5\n6\nseven\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n"),
+		interdiffTestFile(t, "gone.txt", "x\n", "y\n"),
+		interdiffTestFile(t, "same.txt", "p\n", "q\n"),
+	}}
+	v2 := &Diff{Files: []*FileDiff{
+		interdiffTestFile(t, "a.txt", rebased, "0\n0\n1\nTWO\n3\n4\n5\n6\nSEVEN\n7\n8\n9\n10\n11\n12\n13\n14\nfifteen\n16\n"),
+		interdiffTestFile(t, "new.txt", "", "n\n"),
+		interdiffTestFile(t, "same.txt", "p\n", "q\n"),
+	}}
+
+	result := Interdiff(v1, v2)
+	if len(result.Files) != 4 {
+		t.Fatalf("expected 4 files, got %d", len(result.Files))
+	}
+	wantFiles := []InterdiffStatus{InterdiffModified, InterdiffDropped, InterdiffAdded, InterdiffUnchanged}
+	for i, want := range wantFiles {
+		if got := result.Files[i].Status; got != want {
+			t.Errorf("file %d (%s): status = %d, want %d", i, result.Files[i].path(), got, want)
+		}
+	}
+
+	wantHunks := []InterdiffStatus{InterdiffUnchanged, InterdiffModified, InterdiffAdded}
+	hunks := result.Files[0].Hunks
+	if len(hunks) != len(wantHunks) {
+		t.Fatalf("expected %d hunks, got %d", len(wantHunks), len(hunks))
+	}
+	for i, want := range wantHunks {
+		if hunks[i].Status != want {
+			t.Errorf("hunk %d: status = %d, want %d", i, hunks[i].Status, want)
+		}
+	}
+
+	want := `  ## a.txt ##
+@@ a.txt
+  @@
+  6
+-+seven
+++SEVEN
+  7
+@@ a.txt
++ @@
++ 14
++-15
+++fifteen
++ 16
+
+- ## gone.txt ##
+@@ gone.txt
+- @@
+--x
+-+y
+
++ ## new.txt ##
+@@ new.txt
++ @@
+++n
+`
+	if got := result.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestInterdiffDroppedHunkOrder(t *testing.T) {
+	base := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+	v1 := &Diff{Files: []*FileDiff{interdiffTestFile(t, "f", base, "one\n2\n3\n4\n5\n6\n7\n8\nnine\n")}}
+	v2 := &Diff{Files: []*FileDiff{interdiffTestFile(t, "f", base, "1\n2\n3\n4\n5\n6\n7\n8\nnine\n")}}
+
+	hunks := Interdiff(v1, v2).Files[0].Hunks
+	if len(hunks) != 2 || hunks[0].Status != InterdiffDropped || hunks[1].Status != InterdiffUnchanged {
+		t.Fatalf("expected a dropped hunk before an unchanged one, got %d hunks", len(hunks))
+	}
+}
+
+func TestInterdiffSamePathEntries(t *testing.T) {
+	// A file replaced by a link, as git writes it: a deletion and a
+	// creation of the same path.
+	replaced := func(target string) *Diff {
+		return &Diff{Files: []*FileDiff{
+			{Status: FileStatusDeleted, OldPath: "p", NewPath: "p", OldMode: "100644",
+				Hunks: []*Hunk{{OldStart: 1, OldLineCount: 1, Lines: []*HunkLine{{Type: HunkLineDeleted, Content: "text\n"}}}}},
+			{Status: FileStatusNew, OldPath: "p", NewPath: "p", NewMode: "120000",
+				Hunks: []*Hunk{{NewStart: 1, NewLineCount: 1, Lines: []*HunkLine{{Type: HunkLineAdded, Content: target}}}}},
+		}}
+	}
+
+	result := Interdiff(replaced("a"), replaced("b"))
+	if len(result.Files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(result.Files))
+	}
+	for _, file := range result.Files {
+		if file.V1 == nil || file.V2 == nil || file.V1.Status != file.V2.Status {
+			t.Fatalf("expected entries to pair up by status, got %+v", file)
+		}
+	}
+	if got := []InterdiffStatus{result.Files[0].Status, result.Files[1].Status}; got[0] != InterdiffUnchanged || got[1] != InterdiffModified {
+		t.Errorf("statuses = %v, want the deletion unchanged and the creation modified", got)
+	}
+
+	want := "  ## p (new) ##\n@@ p\n  @@\n-+a\n++b\n \\ No newline at end of file\n"
+	if got := result.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,140 @@
+package godiffy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultWordRegex splits lines into whitespace separated words, which is
+// git's default for --word-diff.
+const DefaultWordRegex = `\S+`
+
+func DefaultIntralineOptions() *IntralineOptions {
+	return &IntralineOptions{
+		Mode:      IntralineModeWord,
+		WordRegex: DefaultWordRegex,
+	}
+}
+
+// IntralineDiff pairs each run of deleted lines in hunk with the run of added
+// lines that directly follows it, and diffs the two runs word by word or
+// character by character. Runs without a counterpart are left out. A nil
+// opts uses DefaultIntralineOptions.
+func IntralineDiff(hunk *Hunk, opts *IntralineOptions) ([]*LinePair, error) {
+	if opts == nil {
+		opts = DefaultIntralineOptions()
+	}
+
+	var tokenize func(text string) []string
+	switch opts.Mode {
+	case IntralineModeWord:
+		expr := opts.WordRegex
+		if expr == "" {
+			expr = DefaultWordRegex
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid word regex %s: %w", expr, err)
+		}
+		tokenize = func(text string) []string {
+			return splitWords(text, re)
+		}
+	case IntralineModeChar:
+		tokenize = splitChars
+	default:
+		return nil, fmt.Errorf("unknown intraline mode: %d", opts.Mode)
+	}
+
+	var pairs []*LinePair
+	for i := 0; i < len(hunk.Lines); {
+		if hunk.Lines[i].Type != HunkLineDeleted {
+			i++
+			continue
+		}
+		start := i
+		for i < len(hunk.Lines) && hunk.Lines[i].Type == HunkLineDeleted {
+			i++
+		}
+		middle := i
+		for i < len(hunk.Lines) && hunk.Lines[i].Type == HunkLineAdded {
+			i++
+		}
+		if middle == i {
+			continue
+		}
+
+		pair := &LinePair{Deleted: hunk.Lines[start:middle], Added: hunk.Lines[middle:i]}
+		pair.Segments = diffTokens(tokenize(joinLines(pair.Deleted)), tokenize(joinLines(pair.Added)))
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+func joinLines(lines []*HunkLine) string {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line.Content)
+	}
+	return b.String()
+}
+
+// splitWords cuts text into the matches of re and the text between them, so
+// that the tokens always add up to the whole text.
+func splitWords(text string, re *regexp.Regexp) []string {
+	var tokens []string
+	pos := 0
+	for _, match := range re.FindAllStringIndex(text, -1) {
+		if match[0] == match[1] {
+			continue
+		}
+		if pos < match[0] {
+			tokens = append(tokens, text[pos:match[0]])
+		}
+		tokens = append(tokens, text[match[0]:match[1]])
+		pos = match[1]
+	}
+	if pos < len(text) {
+		tokens = append(tokens, text[pos:])
+	}
+	return tokens
+}
+
+func splitChars(text string) []string {
+	tokens := make([]string, 0, len(text))
+	for _, r := range text {
+		tokens = append(tokens, string(r))
+	}
+	return tokens
+}
+
+// diffTokens runs the line diff machinery on tokens instead of lines and
+// merges neighbouring tokens of the same kind into segments.
+func diffTokens(oldTokens, newTokens []string) []*Segment {
+	oldIDs, newIDs := classifyLines(oldTokens, newTokens)
+	oldChanged, newChanged := myersDiff(oldIDs, newIDs)
+	oldSide := newCompactSide(oldIDs, oldTokens, oldChanged)
+	newSide := newCompactSide(newIDs, newTokens, newChanged)
+	compactChanges(oldSide, newSide, false)
+	compactChanges(newSide, oldSide, false)
+
+	var segments []*Segment
+	add := func(kind HunkLineKind, tokens []string) {
+		for _, token := range tokens {
+			if n := len(segments); n > 0 && segments[n-1].Type == kind {
+				segments[n-1].Text += token
+				continue
+			}
+			segments = append(segments, &Segment{Type: kind, Text: token})
+		}
+	}
+	i2 := 0
+	for _, c := range buildScript(oldSide.result(), newSide.result()) {
+		add(HunkLineContext, newTokens[i2:c.i2])
+		add(HunkLineDeleted, oldTokens[c.i1:c.i1+c.chg1])
+		add(HunkLineAdded, newTokens[c.i2:c.i2+c.chg2])
+		i2 = c.i2 + c.chg2
+	}
+	add(HunkLineContext, newTokens[i2:])
+	return segments
+}
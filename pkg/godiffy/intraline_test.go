@@ -0,0 +1,112 @@
+package godiffy
+
+import (
+	"strings"
+	"testing"
+)
+
+// segmentsString renders segments like git's --word-diff=plain.
+func segmentsString(segments []*Segment) string {
+	var b strings.Builder
+	for _, s := range segments {
+		switch s.Type {
+		case HunkLineDeleted:
+			b.WriteString("[-" + s.Text + "-]")
+		case HunkLineAdded:
+			b.WriteString("{+" + s.Text + "+}")
+		default:
+			b.WriteString(s.Text)
+		}
+	}
+	return b.String()
+}
+
+func TestIntralineDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		opts     *IntralineOptions
+		want     string
+	}{
+		{
+			name: "words",
+			old:  "foo bar baz\n",
+			new:  "foo qux baz\n",
+			want: "foo [-bar-]{+qux+} baz\n",
+		},
+		{
+			name: "characters",
+			old:  "color\n",
+			new:  "colour\n",
+			opts: &IntralineOptions{Mode: IntralineModeChar},
+			want: "colo{+u+}r\n",
+		},
+		{
+			name: "word regex",
+			old:  "call(a, b)\n",
+			new:  "call(a, c)\n",
+			opts: &IntralineOptions{Mode: IntralineModeWord, WordRegex: `[a-z]+|[^a-z ]`},
+			want: "call(a, [-b-]{+c+})\n",
+		},
+		{
+			name: "multiple lines",
+			old:  "one two\nthree four\n",
+			new:  "one 2\nthree 4\n",
+			want: "one [-two-]{+2+}\nthree [-four-]{+4+}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Compute(tt.old, tt.new, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pairs, err := IntralineDiff(file.Hunks[0], tt.opts)
+			if err != nil {
+				t.Fatalf("IntralineDiff returned error: %v", err)
+			}
+			if len(pairs) != 1 {
+				t.Fatalf("expected 1 pair, got %d", len(pairs))
+			}
+			if got := segmentsString(pairs[0].Segments); got != tt.want {
+				t.Errorf("segments = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntralineDiffPairing(t *testing.T) {
+	hunk := &Hunk{Lines: []*HunkLine{
+		{Type: HunkLineContext, Content: "a\n"},
+		{Type: HunkLineDeleted, Content: "b\n"},
+		{Type: HunkLineDeleted, Content: "c\n"},
+		{Type: HunkLineAdded, Content: "B\n"},
+		{Type: HunkLineContext, Content: "d\n"},
+		{Type: HunkLineDeleted, Content: "e\n"},
+		{Type: HunkLineContext, Content: "f\n"},
+		{Type: HunkLineAdded, Content: "g\n"},
+	}}
+	pairs, err := IntralineDiff(hunk, nil)
+	if err != nil {
+		t.Fatalf("IntralineDiff returned error: %v", err)
+	}
+	if len(pairs) != 1 {
+		t.Fatalf("expected 1 pair, got %d", len(pairs))
+	}
+	if len(pairs[0].Deleted) != 2 || len(pairs[0].Added) != 1 {
+		t.Errorf("expected 2 deleted and 1 added line, got %d and %d", len(pairs[0].Deleted), len(pairs[0].Added))
+	}
+	if got, want := segmentsString(pairs[0].Segments), "[-b\nc-]{+B+}\n"; got != want {
+		t.Errorf("segments = %q, want %q", got, want)
+	}
+}
+
+func TestIntralineDiffInvalidOptions(t *testing.T) {
+	hunk := &Hunk{}
+	if _, err := IntralineDiff(hunk, &IntralineOptions{WordRegex: "("}); err == nil {
+		t.Error("expected error for invalid word regex, got nil")
+	}
+	if _, err := IntralineDiff(hunk, &IntralineOptions{Mode: 42}); err == nil {
+		t.Error("expected error for unknown mode, got nil")
+	}
+}
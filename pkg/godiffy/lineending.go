@@ -0,0 +1,41 @@
+package godiffy
+
+import "strings"
+
+// Ending returns the terminator the line has in the diff. Content keeps it,
+// "\r\n" included, so a patch of a CRLF file is not mistaken for one of an
+// LF file.
+func (l *HunkLine) Ending() LineEnding {
+	switch {
+	case strings.HasSuffix(l.Content, "\r\n"):
+		return LineEndingCRLF
+	case strings.HasSuffix(l.Content, "\n"):
+		return LineEndingLF
+	}
+	return LineEndingNone
+}
+
+// Text returns the content of the line without its terminator.
+func (l *HunkLine) Text() string {
+	return TrimLineEnding(l.Content)
+}
+
+// String returns the terminator itself: "", "\n" or "\r\n".
+func (e LineEnding) String() string {
+	switch e {
+	case LineEndingLF:
+		return "\n"
+	case LineEndingCRLF:
+		return "\r\n"
+	}
+	return ""
+}
+
+// TrimLineEnding removes a trailing "\n" or "\r\n" from line.
+func TrimLineEnding(line string) string {
+	line, ok := strings.CutSuffix(line, "\n")
+	if ok {
+		line = strings.TrimSuffix(line, "\r")
+	}
+	return line
+}
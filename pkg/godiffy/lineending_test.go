@@ -0,0 +1,51 @@
+package godiffy
+
+import "testing"
+
+func TestHunkLineEnding(t *testing.T) {
+	input := "diff --git a/x.txt b/x.txt\nindex 1234567..89abcde 100644\n--- a/x.txt\n+++ b/x.txt\n" +
+		"@@ -1,3 +1,3 @@\n one\r\n-two\r\n+zwei\n three\r\n\\ No newline at end of file\n"
+	diff, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	want := []struct {
+		content string
+		ending  LineEnding
+		text    string
+	}{
+		{"one\r\n", LineEndingCRLF, "one"},
+		{"two\r\n", LineEndingCRLF, "two"},
+		{"zwei\n", LineEndingLF, "zwei"},
+		{"three\r", LineEndingNone, "three\r"},
+	}
+	lines := diff.Files[0].Hunks[0].Lines
+	for i, w := range want {
+		if got := lines[i].Content; got != w.content {
+			t.Errorf("line %d: Content = %q, want %q with its terminator", i, got, w.content)
+		}
+		if got := lines[i].Text() + lines[i].Ending().String(); got != lines[i].Content {
+			t.Errorf("line %d: Text() + Ending() = %q, want Content %q", i, got, lines[i].Content)
+		}
+		if got := lines[i].Ending(); got != w.ending {
+			t.Errorf("line %d: Ending() = %d, want %d", i, got, w.ending)
+		}
+		if got := lines[i].Text(); got != w.text {
+			t.Errorf("line %d: Text() = %q, want %q", i, got, w.text)
+		}
+	}
+	if got := diff.String(); got != input {
+		t.Errorf("round trip:\n%q\nwant\n%q", got, input)
+	}
+}
+
+func TestTrimLineEnding(t *testing.T) {
+	for line, want := range map[string]string{"a\r\n": "a", "a\n": "a", "a": "a", "a\r": "a\r", "\n": ""} {
+		if got := TrimLineEnding(line); got != want {
+			t.Errorf("TrimLineEnding(%q) = %q, want %q", line, got, want)
+		}
+	}
+	if LineEndingCRLF.String() != "\r\n" || LineEndingLF.String() != "\n" || LineEndingNone.String() != "" {
+		t.Errorf("LineEnding.String() is wrong")
+	}
+}
@@ -0,0 +1,95 @@
+package godiffy
+
+import "sort"
+
+// LineMap translates line numbers between the old and the new version of a
+// file. Build it with NewLineMap.
+type LineMap struct {
+	hunks []lineMapHunk
+}
+
+// lineMapHunk holds the 1-based first line of each side of a hunk, even for
+// empty ranges, whose header names the line before them.
+type lineMapHunk struct {
+	start [2]int
+	count [2]int
+	lines []*HunkLine
+}
+
+const (
+	sideOld = 0
+	sideNew = 1
+)
+
+func NewLineMap(file *FileDiff) *LineMap {
+	m := &LineMap{hunks: make([]lineMapHunk, 0, len(file.Hunks))}
+	for _, hunk := range file.Hunks {
+		m.hunks = append(m.hunks, lineMapHunk{
+			start: [2]int{
+				firstLineIndex(hunk.OldStart, hunk.OldLineCount) + 1,
+				firstLineIndex(hunk.NewStart, hunk.NewLineCount) + 1,
+			},
+			count: [2]int{hunk.OldLineCount, hunk.NewLineCount},
+			lines: hunk.Lines,
+		})
+	}
+	return m
+}
+
+// OldToNew returns the line of the new file that line of the old file
+// became. It returns false if the diff deletes the line.
+func (m *LineMap) OldToNew(line int) (int, bool) {
+	return m.mapLine(line, sideOld)
+}
+
+// NewToOld returns the line of the old file that line of the new file came
+// from. It returns false if the diff adds the line.
+func (m *LineMap) NewToOld(line int) (int, bool) {
+	return m.mapLine(line, sideNew)
+}
+
+func (m *LineMap) mapLine(line, from int) (int, bool) {
+	if line < 1 {
+		return 0, false
+	}
+	to := 1 - from
+	// The first hunk that does not end before the line.
+	i := sort.Search(len(m.hunks), func(i int) bool {
+		h := m.hunks[i]
+		return h.start[from]+h.count[from] > line
+	})
+	if i == len(m.hunks) || line < m.hunks[i].start[from] {
+		// The line is outside of every hunk and moves with the hunks
+		// before it.
+		if i == 0 {
+			return line, true
+		}
+		h := m.hunks[i-1]
+		return line - (h.start[from] + h.count[from]) + h.start[to] + h.count[to], true
+	}
+
+	h := m.hunks[i]
+	pos := h.start
+	removed, added := HunkLineDeleted, HunkLineAdded
+	if from == sideNew {
+		removed, added = added, removed
+	}
+	for _, l := range h.lines {
+		switch l.Type {
+		case HunkLineContext:
+			if pos[from] == line {
+				return pos[to], true
+			}
+			pos[from]++
+			pos[to]++
+		case removed:
+			if pos[from] == line {
+				return 0, false
+			}
+			pos[from]++
+		case added:
+			pos[to]++
+		}
+	}
+	return 0, false
+}
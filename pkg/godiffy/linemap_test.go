@@ -0,0 +1,77 @@
+package godiffy
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLineMap(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 1; i <= 20; i++ {
+		oldLines = append(oldLines, strconv.Itoa(i)+"\n")
+	}
+	// Insert two lines after 3, delete 8 and 9, replace 15.
+	for i := 1; i <= 20; i++ {
+		switch i {
+		case 8, 9:
+			continue
+		case 15:
+			newLines = append(newLines, "fifteen\n")
+			continue
+		}
+		newLines = append(newLines, strconv.Itoa(i)+"\n")
+		if i == 3 {
+			newLines = append(newLines, "a\n", "b\n")
+		}
+	}
+	file, err := Compute(strings.Join(oldLines, ""), strings.Join(newLines, ""), &DiffOptions{Context: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewLineMap(file)
+
+	// New line number for each old line, 0 for deleted lines.
+	oldToNew := []int{0, 1, 2, 3, 6, 7, 8, 9, 0, 0, 10, 11, 12, 13, 14, 0, 16, 17, 18, 19, 20}
+	for old := 1; old < len(oldToNew); old++ {
+		got, ok := m.OldToNew(old)
+		want := oldToNew[old]
+		if ok != (want != 0) || got != want {
+			t.Errorf("OldToNew(%d) = %d, %v; want %d", old, got, ok, want)
+		}
+	}
+	newToOld := []int{0, 1, 2, 3, 0, 0, 4, 5, 6, 7, 10, 11, 12, 13, 14, 0, 16, 17, 18, 19, 20}
+	for n := 1; n < len(newToOld); n++ {
+		got, ok := m.NewToOld(n)
+		want := newToOld[n]
+		if ok != (want != 0) || got != want {
+			t.Errorf("NewToOld(%d) = %d, %v; want %d", n, got, ok, want)
+		}
+	}
+
+	// Lines past the last hunk keep the final offset.
+	if got, ok := m.OldToNew(120); !ok || got != 120 {
+		t.Errorf("OldToNew(120) = %d, %v; want 120", got, ok)
+	}
+	if _, ok := m.OldToNew(0); ok {
+		t.Error("expected line 0 not to map")
+	}
+}
+
+func TestLineMapNewAndDeletedFiles(t *testing.T) {
+	created, err := Compute("", "a\nb\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := NewLineMap(created).NewToOld(2); ok {
+		t.Error("expected lines of a new file to be added")
+	}
+
+	deleted, err := Compute("a\nb\n", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := NewLineMap(deleted).OldToNew(1); ok {
+		t.Error("expected lines of a deleted file to be deleted")
+	}
+}
@@ -0,0 +1,88 @@
+package godiffy
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	FileModeRegular    FileMode = "100644"
+	FileModeExecutable FileMode = "100755"
+	FileModeSymlink    FileMode = "120000"
+	FileModeGitlink    FileMode = "160000"
+	FileModeTree       FileMode = "040000"
+)
+
+// The object types in the upper bits of a mode.
+const (
+	modeTypeMask    = 0170000
+	modeTypeRegular = 0100000
+	modeTypeSymlink = 0120000
+	modeTypeGitlink = 0160000
+	modeTypeTree    = 0040000
+)
+
+// ParseFileMode checks that s is a mode git could have written: six octal
+// digits for a file, a link, a gitlink or a tree.
+func ParseFileMode(s string) (FileMode, error) {
+	mode := FileMode(s)
+	bits, err := mode.bits()
+	if err != nil || len(s) != 6 {
+		return "", fmt.Errorf("invalid file mode: %s", s)
+	}
+	switch bits & modeTypeMask {
+	case modeTypeRegular, modeTypeSymlink, modeTypeGitlink, modeTypeTree:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid file mode: %s", s)
+}
+
+func (m FileMode) bits() (uint32, error) {
+	n, err := strconv.ParseUint(string(m), 8, 32)
+	return uint32(n), err
+}
+
+// IsRegular reports whether the mode is that of a regular file, executable
+// or not.
+func (m FileMode) IsRegular() bool {
+	bits, err := m.bits()
+	return err == nil && bits&modeTypeMask == modeTypeRegular
+}
+
+// IsExecutable reports whether the mode is that of an executable file.
+func (m FileMode) IsExecutable() bool {
+	bits, _ := m.bits()
+	return m.IsRegular() && bits&0111 != 0
+}
+
+// IsSymlink reports whether the mode is that of a symbolic link.
+func (m FileMode) IsSymlink() bool {
+	return m == FileModeSymlink
+}
+
+// IsGitlink reports whether the mode is that of a submodule commit.
+func (m FileMode) IsGitlink() bool {
+	return m == FileModeGitlink
+}
+
+// OSMode converts the mode to the os.FileMode a file in a work tree gets.
+// Links and gitlinks give os.ModeSymlink and os.ModeDir. Modes without type
+// bits, like "0644", are taken as permissions.
+func (m FileMode) OSMode() (os.FileMode, error) {
+	bits, err := m.bits()
+	if err != nil {
+		return 0, fmt.Errorf("invalid file mode: %s", m)
+	}
+	switch bits & modeTypeMask {
+	case modeTypeSymlink:
+		return os.ModeSymlink | 0777, nil
+	case modeTypeGitlink, modeTypeTree:
+		return os.ModeDir | 0755, nil
+	}
+	return os.FileMode(bits & 0777), nil
+}
+
+func (m FileMode) String() string {
+	return string(m)
+}
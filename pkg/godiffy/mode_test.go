@@ -0,0 +1,78 @@
+package godiffy
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFileMode(t *testing.T) {
+	tests := []struct {
+		mode                                  FileMode
+		regular, executable, symlink, gitlink bool
+		osMode                                os.FileMode
+	}{
+		{FileModeRegular, true, false, false, false, 0644},
+		{FileModeExecutable, true, true, false, false, 0755},
+		{"100664", true, false, false, false, 0664},
+		{FileModeSymlink, false, false, true, false, os.ModeSymlink | 0777},
+		{FileModeGitlink, false, false, false, true, os.ModeDir | 0755},
+		{FileModeTree, false, false, false, false, os.ModeDir | 0755},
+		{"0600", false, false, false, false, 0600},
+	}
+	for _, tt := range tests {
+		if got := tt.mode.IsRegular(); got != tt.regular {
+			t.Errorf("%s.IsRegular() = %v", tt.mode, got)
+		}
+		if got := tt.mode.IsExecutable(); got != tt.executable {
+			t.Errorf("%s.IsExecutable() = %v", tt.mode, got)
+		}
+		if got := tt.mode.IsSymlink(); got != tt.symlink {
+			t.Errorf("%s.IsSymlink() = %v", tt.mode, got)
+		}
+		if got := tt.mode.IsGitlink(); got != tt.gitlink {
+			t.Errorf("%s.IsGitlink() = %v", tt.mode, got)
+		}
+		if got, err := tt.mode.OSMode(); err != nil || got != tt.osMode {
+			t.Errorf("%s.OSMode() = %v, %v, want %v", tt.mode, got, err, tt.osMode)
+		}
+	}
+	if _, err := FileMode("nope").OSMode(); err == nil {
+		t.Errorf("expected an error converting an invalid mode")
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	for _, s := range []string{"100644", "100755", "120000", "160000", "040000"} {
+		if mode, err := ParseFileMode(s); err != nil || string(mode) != s {
+			t.Errorf("ParseFileMode(%s) = %s, %v", s, mode, err)
+		}
+	}
+	for _, s := range []string{"", "644", "0644", "100648", "200644", "1006440", "10064x"} {
+		if _, err := ParseFileMode(s); err == nil {
+			t.Errorf("ParseFileMode(%q) returned no error", s)
+		}
+	}
+}
+
+func TestParseInvalidModesAndHashes(t *testing.T) {
+	for _, input := range []string{
+		"diff --git a/x b/x\nindex abc1234..def5678 100648\n",
+		"diff --git a/x b/x\nindex abc1234..xyz5678 100644\n",
+		"diff --git a/x b/x\nold mode 644\nnew mode 100755\n",
+		"diff --git a/x b/x\nnew file mode 12345\n",
+		"diff --git a/x b/x\ndeleted file mode 100644x\n",
+		"diff --git a/s b/s\nindex 46841a5..9ffcedc 160000\n--- a/s\n+++ b/s\n@@ -1 +1 @@\n-Subproject commit 46841a5\n+Subproject commit nope\n",
+	} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) returned no error", input)
+		}
+	}
+	for _, input := range []string{
+		":100644 100648 abc1234 def5678 M\tfile\n",
+		":100644 100644 abc1234 g M\tfile\n",
+	} {
+		if _, err := ParseRaw(input); err == nil {
+			t.Errorf("ParseRaw(%q) returned no error", input)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package godiffy
+
+import "math"
+
+// Tuning constants of git's xdiff implementation of Myers' algorithm. Using
+// the same values keeps the output identical to "git diff".
+const (
+	myersMaxCostMin   = 256
+	myersHeurMinCost  = 256
+	myersSnakeCount   = 20
+	myersHeurFactor   = 4
+	myersMaxEqLimit   = 1024
+	myersSimscanWnd   = 100
+	myersDiscardRatio = 4
+)
+
+type myersSplit struct {
+	i1, i2       int
+	minLo, minHi bool
+}
+
+type myers struct {
+	ha1, ha2         []int // line ids of the records that survived discarding
+	rindex1, rindex2 []int // maps records back to line numbers
+	changed1         []bool
+	changed2         []bool
+	kvdf, kvdb       []int
+	koff             int
+	maxCost          int
+}
+
+// myersDiff marks the changed lines of a and b, where equal lines share the
+// same id. It follows xdiff's classic algorithm: the common prefix and suffix
+// are trimmed, lines without a counterpart are discarded up front and the
+// rest is split recursively along the middle snake of the edit graph.
+func myersDiff(a, b []int) (changed1, changed2 []bool) {
+	changed1, changed2 = make([]bool, len(a)), make([]bool, len(b))
+
+	lim := min(len(a), len(b))
+	start := 0
+	for start < lim && a[start] == b[start] {
+		start++
+	}
+	end := 0
+	for end < lim-start && a[len(a)-1-end] == b[len(b)-1-end] {
+		end++
+	}
+
+	count1, count2 := make(map[int]int), make(map[int]int)
+	for _, id := range a {
+		count1[id]++
+	}
+	for _, id := range b {
+		count2[id]++
+	}
+
+	m := &myers{changed1: changed1, changed2: changed2}
+	m.ha1, m.rindex1 = discardRecords(a, start, len(a)-end-1, count2, changed1)
+	m.ha2, m.rindex2 = discardRecords(b, start, len(b)-end-1, count1, changed2)
+
+	ndiags := len(m.ha1) + len(m.ha2) + 3
+	m.kvdf = make([]int, ndiags)
+	m.kvdb = make([]int, ndiags)
+	m.koff = len(m.ha2) + 1
+	m.maxCost = max(bogoSqrt(ndiags), myersMaxCostMin)
+
+	m.compare(0, len(m.ha1), 0, len(m.ha2), false)
+	return changed1, changed2
+}
+
+// discardRecords drops the lines in [dstart, dend] that cannot be part of
+// the common subsequence and marks them as changed right away. Lines that
+// occur very often on the other side are dropped too when they sit in a run
+// of unmatched lines.
+func discardRecords(ids []int, dstart, dend int, other map[int]int, changed []bool) (ha, rindex []int) {
+	mlim := min(bogoSqrt(len(ids)), myersMaxEqLimit)
+	dis := make([]byte, len(ids))
+	for i := dstart; i <= dend; i++ {
+		switch nm := other[ids[i]]; {
+		case nm == 0:
+			dis[i] = 0
+		case nm >= mlim:
+			dis[i] = 2
+		default:
+			dis[i] = 1
+		}
+	}
+	for i := dstart; i <= dend; i++ {
+		if dis[i] == 1 || (dis[i] == 2 && !cleanMultiMatch(dis, i, dstart, dend)) {
+			ha = append(ha, ids[i])
+			rindex = append(rindex, i)
+		} else {
+			changed[i] = true
+		}
+	}
+	return ha, rindex
+}
+
+func cleanMultiMatch(dis []byte, i, s, e int) bool {
+	if i-s > myersSimscanWnd {
+		s = i - myersSimscanWnd
+	}
+	if e-i > myersSimscanWnd {
+		e = i + myersSimscanWnd
+	}
+
+	rdis0, rpdis0 := 0, 1
+	for r := 1; i-r >= s; r++ {
+		if dis[i-r] == 0 {
+			rdis0++
+		} else if dis[i-r] == 2 {
+			rpdis0++
+		} else {
+			break
+		}
+	}
+	if rdis0 == 0 {
+		return false
+	}
+	rdis1, rpdis1 := 0, 1
+	for r := 1; i+r <= e; r++ {
+		if dis[i+r] == 0 {
+			rdis1++
+		} else if dis[i+r] == 2 {
+			rpdis1++
+		} else {
+			break
+		}
+	}
+	if rdis1 == 0 {
+		return false
+	}
+	rdis1 += rdis0
+	rpdis1 += rpdis0
+	return rpdis1*myersDiscardRatio < rpdis1+rdis1
+}
+
+func (m *myers) compare(off1, lim1, off2, lim2 int, needMin bool) {
+	for off1 < lim1 && off2 < lim2 && m.ha1[off1] == m.ha2[off2] {
+		off1++
+		off2++
+	}
+	for off1 < lim1 && off2 < lim2 && m.ha1[lim1-1] == m.ha2[lim2-1] {
+		lim1--
+		lim2--
+	}
+
+	switch {
+	case off1 == lim1:
+		for ; off2 < lim2; off2++ {
+			m.changed2[m.rindex2[off2]] = true
+		}
+	case off2 == lim2:
+		for ; off1 < lim1; off1++ {
+			m.changed1[m.rindex1[off1]] = true
+		}
+	default:
+		spl := m.split(off1, lim1, off2, lim2, needMin)
+		m.compare(off1, spl.i1, off2, spl.i2, spl.minLo)
+		m.compare(spl.i1, lim1, spl.i2, lim2, spl.minHi)
+	}
+}
+
+// split finds the point where the forward and backward searches for the
+// shortest edit script meet. When the search gets too expensive it settles
+// for a good-enough point instead, unless needMin is set.
+func (m *myers) split(off1, lim1, off2, lim2 int, needMin bool) myersSplit {
+	ha1, ha2 := m.ha1, m.ha2
+	kvdf := func(d int) *int { return &m.kvdf[m.koff+d] }
+	kvdb := func(d int) *int { return &m.kvdb[m.koff+d] }
+
+	dmin, dmax := off1-lim2, lim1-off2
+	fmid, bmid := off1-off2, lim1-lim2
+	odd := (fmid-bmid)&1 != 0
+	fmin, fmax := fmid, fmid
+	bmin, bmax := bmid, bmid
+
+	*kvdf(fmid) = off1
+	*kvdb(bmid) = lim1
+
+	for ec := 1; ; ec++ {
+		gotSnake := false
+
+		if fmin > dmin {
+			fmin--
+			*kvdf(fmin - 1) = -1
+		} else {
+			fmin++
+		}
+		if fmax < dmax {
+			fmax++
+			*kvdf(fmax + 1) = -1
+		} else {
+			fmax--
+		}
+
+		for d := fmax; d >= fmin; d -= 2 {
+			var i1 int
+			if *kvdf(d - 1) >= *kvdf(d + 1) {
+				i1 = *kvdf(d - 1) + 1
+			} else {
+				i1 = *kvdf(d + 1)
+			}
+			prev1 := i1
+			i2 := i1 - d
+			for i1 < lim1 && i2 < lim2 && ha1[i1] == ha2[i2] {
+				i1++
+				i2++
+			}
+			if i1-prev1 > myersSnakeCount {
+				gotSnake = true
+			}
+			*kvdf(d) = i1
+			if odd && bmin <= d && d <= bmax && *kvdb(d) <= i1 {
+				return myersSplit{i1: i1, i2: i2, minLo: true, minHi: true}
+			}
+		}
+
+		if bmin > dmin {
+			bmin--
+			*kvdb(bmin - 1) = math.MaxInt
+		} else {
+			bmin++
+		}
+		if bmax < dmax {
+			bmax++
+			*kvdb(bmax + 1) = math.MaxInt
+		} else {
+			bmax--
+		}
+
+		for d := bmax; d >= bmin; d -= 2 {
+			var i1 int
+			if *kvdb(d - 1) < *kvdb(d + 1) {
+				i1 = *kvdb(d - 1)
+			} else {
+				i1 = *kvdb(d + 1) - 1
+			}
+			prev1 := i1
+			i2 := i1 - d
+			for i1 > off1 && i2 > off2 && ha1[i1-1] == ha2[i2-1] {
+				i1--
+				i2--
+			}
+			if prev1-i1 > myersSnakeCount {
+				gotSnake = true
+			}
+			*kvdb(d) = i1
+			if !odd && fmin <= d && d <= fmax && i1 <= *kvdf(d) {
+				return myersSplit{i1: i1, i2: i2, minLo: true, minHi: true}
+			}
+		}
+
+		if needMin {
+			continue
+		}
+
+		// Once the edit cost is high and a long snake was seen, accept a
+		// diagonal that got far enough from its corner.
+		if gotSnake && ec > myersHeurMinCost {
+			var spl myersSplit
+			best := 0
+			for d := fmax; d >= fmin; d -= 2 {
+				dd := abs(d - fmid)
+				i1 := *kvdf(d)
+				i2 := i1 - d
+				v := (i1 - off1) + (i2 - off2) - dd
+				if v > myersHeurFactor*ec && v > best &&
+					off1+myersSnakeCount <= i1 && i1 < lim1 &&
+					off2+myersSnakeCount <= i2 && i2 < lim2 {
+					for k := 1; ha1[i1-k] == ha2[i2-k]; k++ {
+						if k == myersSnakeCount {
+							best = v
+							spl.i1, spl.i2 = i1, i2
+							break
+						}
+					}
+				}
+			}
+			if best > 0 {
+				spl.minLo, spl.minHi = true, false
+				return spl
+			}
+
+			best = 0
+			for d := bmax; d >= bmin; d -= 2 {
+				dd := abs(d - bmid)
+				i1 := *kvdb(d)
+				i2 := i1 - d
+				v := (lim1 - i1) + (lim2 - i2) - dd
+				if v > myersHeurFactor*ec && v > best &&
+					off1 < i1 && i1 <= lim1-myersSnakeCount &&
+					off2 < i2 && i2 <= lim2-myersSnakeCount {
+					for k := 0; ha1[i1+k] == ha2[i2+k]; k++ {
+						if k == myersSnakeCount-1 {
+							best = v
+							spl.i1, spl.i2 = i1, i2
+							break
+						}
+					}
+				}
+			}
+			if best > 0 {
+				spl.minLo, spl.minHi = false, true
+				return spl
+			}
+		}
+
+		// Enough is enough: take the furthest reaching path found so far.
+		if ec >= m.maxCost {
+			fbest, fbest1 := -1, -1
+			for d := fmax; d >= fmin; d -= 2 {
+				i1 := min(*kvdf(d), lim1)
+				i2 := i1 - d
+				if lim2 < i2 {
+					i1 = lim2 + d
+					i2 = lim2
+				}
+				if fbest < i1+i2 {
+					fbest = i1 + i2
+					fbest1 = i1
+				}
+			}
+
+			bbest, bbest1 := math.MaxInt, math.MaxInt
+			for d := bmax; d >= bmin; d -= 2 {
+				i1 := max(off1, *kvdb(d))
+				i2 := i1 - d
+				if i2 < off2 {
+					i1 = off2 + d
+					i2 = off2
+				}
+				if i1+i2 < bbest {
+					bbest = i1 + i2
+					bbest1 = i1
+				}
+			}
+
+			if (lim1+lim2)-bbest < fbest-(off1+off2) {
+				return myersSplit{i1: fbest1, i2: fbest - fbest1, minLo: true}
+			}
+			return myersSplit{i1: bbest1, i2: bbest - bbest1, minHi: true}
+		}
+	}
+}
+
+func bogoSqrt(n int) int {
+	i := 1
+	for ; n > 0; n >>= 2 {
+		i <<= 1
+	}
+	return i
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,126 @@
+package godiffy
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// MinAbbrevLength is the shortest object ID prefix git accepts.
+const MinAbbrevLength = 4
+
+// HexLength returns the length of a full object ID in hex digits.
+func (f ObjectFormat) HexLength() int {
+	switch f {
+	case ObjectFormatSHA256:
+		return sha256.Size * 2
+	default:
+		return sha1.Size * 2
+	}
+}
+
+func (f ObjectFormat) newHash() hash.Hash {
+	switch f {
+	case ObjectFormatSHA256:
+		return sha256.New()
+	default:
+		return sha1.New()
+	}
+}
+
+// NullObjectID returns the all-zero ID git uses for the missing side of a
+// new or deleted file.
+func NullObjectID(format ObjectFormat) ObjectID {
+	return ObjectID(strings.Repeat("0", format.HexLength()))
+}
+
+// BlobID computes the ID git gives data stored as a blob, which is the hash
+// of "blob <len>\0" followed by the data.
+func BlobID(data []byte, format ObjectFormat) ObjectID {
+	h := format.newHash()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	h.Write(data)
+	return ObjectID(hex.EncodeToString(h.Sum(nil)))
+}
+
+// padHex makes an odd-length hex prefix decodable.
+func padHex(id string) string {
+	if len(id)%2 == 1 {
+		return id + "0"
+	}
+	return id
+}
+
+// ParseObjectID checks that s is a full or abbreviated object ID: hex
+// digits, no longer than a SHA-256 ID.
+func ParseObjectID(s string) (ObjectID, error) {
+	if s == "" || len(s) > ObjectFormatSHA256.HexLength() {
+		return "", fmt.Errorf("invalid object ID: %s", s)
+	}
+	if _, err := hex.DecodeString(padHex(s)); err != nil {
+		return "", fmt.Errorf("invalid object ID: %s", s)
+	}
+	return ObjectID(s), nil
+}
+
+// Format returns the object format of a full ID. It reports false for an
+// abbreviated ID, whose format cannot be told from its length.
+func (id ObjectID) Format() (ObjectFormat, bool) {
+	switch len(id) {
+	case ObjectFormatSHA1.HexLength():
+		return ObjectFormatSHA1, true
+	case ObjectFormatSHA256.HexLength():
+		return ObjectFormatSHA256, true
+	}
+	return ObjectFormatSHA1, false
+}
+
+// IsAbbreviated reports whether the ID is shorter than a full ID.
+func (id ObjectID) IsAbbreviated() bool {
+	_, full := id.Format()
+	return !full
+}
+
+// Abbrev shortens the ID to n hex digits, as git does on index lines.
+func (id ObjectID) Abbrev(n int) ObjectID {
+	if n >= len(id) {
+		return id
+	}
+	return id[:n]
+}
+
+// IsNull reports whether the ID consists of zeros only.
+func (id ObjectID) IsNull() bool {
+	return id != "" && strings.Trim(string(id), "0") == ""
+}
+
+// Match reports whether the ID, full or abbreviated, names the full ID.
+// Case is ignored, and prefixes shorter than MinAbbrevLength never match.
+func (id ObjectID) Match(full ObjectID) bool {
+	if len(id) < MinAbbrevLength || len(id) > len(full) {
+		return false
+	}
+	if _, err := hex.DecodeString(padHex(string(id))); err != nil {
+		return false
+	}
+	return strings.EqualFold(string(id), string(full[:len(id)]))
+}
+
+// MatchBlob reports whether the ID, full or abbreviated as found on an
+// index line, is the blob ID of data in any object format it could belong
+// to.
+func (id ObjectID) MatchBlob(data []byte) bool {
+	for _, format := range []ObjectFormat{ObjectFormatSHA1, ObjectFormatSHA256} {
+		if len(id) <= format.HexLength() && id.Match(BlobID(data, format)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (id ObjectID) String() string {
+	return string(id)
+}
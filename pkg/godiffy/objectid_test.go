@@ -0,0 +1,122 @@
+package godiffy
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestBlobID(t *testing.T) {
+	tests := []struct {
+		data   string
+		format ObjectFormat
+		want   ObjectID
+	}{
+		{"", ObjectFormatSHA1, "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"},
+		{"hello\n", ObjectFormatSHA1, "ce013625030ba8dba906f756967f9e9ca394464a"},
+		{"", ObjectFormatSHA256, "473a0f4c3be8a93681a267e3b1e9a7dcda1185436fe141f7749120a303721813"},
+		{"hello\n", ObjectFormatSHA256, "2cf8d83d9ee29543b34a87727421fdecb7e3f3a183d337639025de576db9ebb4"},
+	}
+	for _, tt := range tests {
+		if got := BlobID([]byte(tt.data), tt.format); got != tt.want {
+			t.Errorf("BlobID(%q, %d) = %s, want %s", tt.data, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestObjectIDMatch(t *testing.T) {
+	full := ObjectID("ce013625030ba8dba906f756967f9e9ca394464a")
+	tests := []struct {
+		id   ObjectID
+		want bool
+	}{
+		{full, true},
+		{"ce01362", true},
+		{"CE01362", true},
+		{"ce013", true},
+		{"ce0", false},
+		{"ce01363", false},
+		{"ce0136zz", false},
+		{full + "00", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := tt.id.Match(full); got != tt.want {
+			t.Errorf("ObjectID(%q).Match = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestObjectIDMatchBlob(t *testing.T) {
+	data := []byte("hello\n")
+	for _, id := range []ObjectID{"ce01362", "2cf8d83d9ee2", "2cf8d83d9ee29543b34a87727421fdecb7e3f3a183d337639025de576db9ebb4"} {
+		if !id.MatchBlob(data) {
+			t.Errorf("expected %s to match", id)
+		}
+	}
+	if ObjectID("e69de29").MatchBlob(data) {
+		t.Errorf("expected empty blob ID not to match")
+	}
+}
+
+func TestObjectIDIsNull(t *testing.T) {
+	if !ObjectID("0000000").IsNull() || !NullObjectID(ObjectFormatSHA256).IsNull() {
+		t.Errorf("expected zero IDs to be null")
+	}
+	if ObjectID("").IsNull() || ObjectID("0000001").IsNull() {
+		t.Errorf("expected empty and non-zero IDs not to be null")
+	}
+}
+
+func TestDiffFSObjectFormat(t *testing.T) {
+	opts := DefaultTreeDiffOptions()
+	opts.ObjectFormat = ObjectFormatSHA256
+	opts.FullIndex = true
+	diff, err := DiffFS(fstest.MapFS{}, fstest.MapFS{"a.txt": {Data: []byte("hello\n")}}, opts)
+	if err != nil {
+		t.Fatalf("DiffFS returned error: %v", err)
+	}
+	file := diff.Files[0]
+	if file.OldHash != NullObjectID(ObjectFormatSHA256) {
+		t.Errorf("expected SHA-256 null ID, got %s", file.OldHash)
+	}
+	if file.NewHash != BlobID([]byte("hello\n"), ObjectFormatSHA256) {
+		t.Errorf("expected SHA-256 blob ID, got %s", file.NewHash)
+	}
+}
+
+func TestObjectID(t *testing.T) {
+	sha1ID := BlobID([]byte("hello\n"), ObjectFormatSHA1)
+	sha256ID := BlobID([]byte("hello\n"), ObjectFormatSHA256)
+	if format, ok := sha1ID.Format(); !ok || format != ObjectFormatSHA1 || sha1ID.IsAbbreviated() {
+		t.Errorf("%s.Format() = %d, %v", sha1ID, format, ok)
+	}
+	if format, ok := sha256ID.Format(); !ok || format != ObjectFormatSHA256 {
+		t.Errorf("%s.Format() = %d, %v", sha256ID, format, ok)
+	}
+	short := sha256ID.Abbrev(7)
+	if short != "2cf8d83" || !short.IsAbbreviated() || !short.MatchBlob([]byte("hello\n")) {
+		t.Errorf("Abbrev(7) = %s", short)
+	}
+	if _, ok := short.Format(); ok {
+		t.Errorf("expected no format for an abbreviated ID")
+	}
+	if got := short.Abbrev(12); got != short {
+		t.Errorf("Abbrev(12) = %s, want %s", got, short)
+	}
+	if !ObjectID("0000000").IsNull() || sha1ID.IsNull() {
+		t.Errorf("IsNull() is wrong")
+	}
+}
+
+func TestParseObjectID(t *testing.T) {
+	for _, s := range []string{"1", "abc1234", "ABC1234", NullObjectID(ObjectFormatSHA256).String()} {
+		if id, err := ParseObjectID(s); err != nil || string(id) != s {
+			t.Errorf("ParseObjectID(%s) = %s, %v", s, id, err)
+		}
+	}
+	for _, s := range []string{"", "xyz", "abc 123", NullObjectID(ObjectFormatSHA256).String() + "0"} {
+		if _, err := ParseObjectID(s); err == nil {
+			t.Errorf("ParseObjectID(%q) returned no error", s)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package godiffy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pathspec is a parsed git pathspec such as "src", "*.go" or
+// ":(exclude,glob)vendor/**".
+type pathspec struct {
+	pattern string
+	exclude bool
+	glob    bool
+	literal bool
+	icase   bool
+}
+
+func parsePathspec(spec string) (*pathspec, error) {
+	ps := &pathspec{pattern: spec}
+	if !strings.HasPrefix(spec, ":") {
+		return ps, nil
+	}
+
+	if strings.HasPrefix(spec, ":(") {
+		magic, pattern, ok := strings.Cut(spec[2:], ")")
+		if !ok {
+			return nil, fmt.Errorf("invalid pathspec format: %s", spec)
+		}
+		for _, word := range strings.Split(magic, ",") {
+			switch strings.TrimSpace(word) {
+			case "exclude":
+				ps.exclude = true
+			case "glob":
+				ps.glob = true
+			case "literal":
+				ps.literal = true
+			case "icase":
+				ps.icase = true
+			case "top", "":
+			default:
+				return nil, fmt.Errorf("invalid pathspec magic %s in %s", word, spec)
+			}
+		}
+		ps.pattern = pattern
+	} else {
+		// The short form is a run of magic characters, optionally ended by
+		// a second colon.
+		rest := spec[1:]
+	short:
+		for len(rest) > 0 {
+			switch rest[0] {
+			case '!', '^':
+				ps.exclude = true
+			case '/':
+			case ':':
+				rest = rest[1:]
+				break short
+			default:
+				break short
+			}
+			rest = rest[1:]
+		}
+		ps.pattern = rest
+	}
+
+	if ps.glob && ps.literal {
+		return nil, fmt.Errorf("invalid pathspec %s: glob and literal cannot be combined", spec)
+	}
+	return ps, nil
+}
+
+// match reports whether name matches the pathspec. Patterns without
+// wildcards also match everything below the directory they name. Without
+// glob magic wildcards match across slashes, like git's default pathspecs.
+func (ps *pathspec) match(name string) bool {
+	pattern := ps.pattern
+	if ps.icase {
+		pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	}
+	if pattern == "" || pattern == "." {
+		return true
+	}
+	if strings.HasPrefix(name, pattern) && (len(name) == len(pattern) || strings.HasSuffix(pattern, "/") || name[len(pattern)] == '/') {
+		return true
+	}
+	if ps.literal || !strings.ContainsAny(pattern, "*?[") {
+		return false
+	}
+	return wildmatch(pattern, name, ps.glob)
+}
+
+// Filter returns the files of d that match at least one of the pathspecs
+// and none of the excluding ones. Renamed and copied files match by either
+// path. With only excluding pathspecs, every other file is kept. The
+// returned diff shares its FileDiffs with d.
+func (d *Diff) Filter(pathspecs ...string) (*Diff, error) {
+	var includes, excludes []*pathspec
+	for _, spec := range pathspecs {
+		ps, err := parsePathspec(spec)
+		if err != nil {
+			return nil, err
+		}
+		if ps.exclude {
+			excludes = append(excludes, ps)
+		} else {
+			includes = append(includes, ps)
+		}
+	}
+
+	matchAny := func(specs []*pathspec, file *FileDiff) bool {
+		oldPath, newPath := file.displayPaths()
+		for _, ps := range specs {
+			if ps.match(oldPath) || ps.match(newPath) {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := &Diff{}
+	for _, file := range d.Files {
+		if len(includes) > 0 && !matchAny(includes, file) {
+			continue
+		}
+		if matchAny(excludes, file) {
+			continue
+		}
+		result.Files = append(result.Files, file)
+	}
+	return result, nil
+}
+
+// wildmatch matches name against a shell wildcard pattern the way git's
+// wildmatch does. With pathname set, wildcards stop at slashes and "**"
+// between slashes matches any number of directories.
+func wildmatch(pattern, name string, pathname bool) bool {
+	p, n := 0, 0
+	for p < len(pattern) {
+		switch c := pattern[p]; c {
+		case '?':
+			if n == len(name) || (pathname && name[n] == '/') {
+				return false
+			}
+			p++
+			n++
+		case '*':
+			start := p
+			for p < len(pattern) && pattern[p] == '*' {
+				p++
+			}
+			if pathname && p-start >= 2 && (start == 0 || pattern[start-1] == '/') && (p == len(pattern) || pattern[p] == '/') {
+				if p == len(pattern) {
+					return true
+				}
+				// "**/" matches nothing or any run of leading directories.
+				if wildmatch(pattern[p+1:], name[n:], pathname) {
+					return true
+				}
+				for i := n; i < len(name); i++ {
+					if name[i] == '/' && wildmatch(pattern[p+1:], name[i+1:], pathname) {
+						return true
+					}
+				}
+				return false
+			}
+			if p == len(pattern) {
+				return !pathname || !strings.Contains(name[n:], "/")
+			}
+			for i := n; i <= len(name); i++ {
+				if wildmatch(pattern[p:], name[i:], pathname) {
+					return true
+				}
+				if i < len(name) && pathname && name[i] == '/' {
+					return false
+				}
+			}
+			return false
+		case '[':
+			if n == len(name) || (pathname && name[n] == '/') {
+				return false
+			}
+			matched, next, ok := matchClass(pattern, p, name[n])
+			if !ok || !matched {
+				return false
+			}
+			p = next
+			n++
+		default:
+			if c == '\\' && p+1 < len(pattern) {
+				p++
+				c = pattern[p]
+			}
+			if n == len(name) || name[n] != c {
+				return false
+			}
+			p++
+			n++
+		}
+	}
+	return n == len(name)
+}
+
+// matchClass matches c against the bracket expression starting at
+// pattern[start]. It returns the index after the closing bracket, and false
+// for ok if the expression is not closed.
+func matchClass(pattern string, start int, c byte) (matched bool, next int, ok bool) {
+	p := start + 1
+	negate := p < len(pattern) && (pattern[p] == '!' || pattern[p] == '^')
+	if negate {
+		p++
+	}
+	for first := true; p < len(pattern); first = false {
+		lo := pattern[p]
+		if lo == ']' && !first {
+			return matched != negate, p + 1, true
+		}
+		if lo == '\\' && p+1 < len(pattern) {
+			p++
+			lo = pattern[p]
+		}
+		hi := lo
+		if p+2 < len(pattern) && pattern[p+1] == '-' && pattern[p+2] != ']' {
+			hi = pattern[p+2]
+			if hi == '\\' && p+3 < len(pattern) {
+				p++
+				hi = pattern[p+2]
+			}
+			p += 2
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+		p++
+	}
+	return false, 0, false
+}
@@ -0,0 +1,125 @@
+package godiffy
+
+import (
+	"strings"
+	"testing"
+)
+
+var pathspecTestPaths = []string{
+	"README.md", "docs/Guide.MD", "foo.c", "main.go", "src/a/b/z.go", "src/a/b/z.txt",
+	"src/a/y.go", "src/foo.c", "src/x.go", "vendor/x/v.go",
+}
+
+func pathspecTestDiff() *Diff {
+	diff := &Diff{}
+	for _, p := range pathspecTestPaths {
+		diff.Files = append(diff.Files, &FileDiff{Status: FileStatusModified, OldPath: p, NewPath: p})
+	}
+	return diff
+}
+
+func filePaths(diff *Diff) string {
+	var paths []string
+	for _, file := range diff.Files {
+		paths = append(paths, file.NewPath)
+	}
+	return strings.Join(paths, " ")
+}
+
+// The expected paths are what "git ls-files" lists for each pathspec.
+func TestFilterMatchesGit(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"src", "src/a/b/z.go src/a/b/z.txt src/a/y.go src/foo.c src/x.go"},
+		{"src/", "src/a/b/z.go src/a/b/z.txt src/a/y.go src/foo.c src/x.go"},
+		{"*.go", "main.go src/a/b/z.go src/a/y.go src/x.go vendor/x/v.go"},
+		{":(glob)*.go", "main.go"},
+		{":(glob)**/*.go", "main.go src/a/b/z.go src/a/y.go src/x.go vendor/x/v.go"},
+		{":(glob)src/**", "src/a/b/z.go src/a/b/z.txt src/a/y.go src/foo.c src/x.go"},
+		{":(glob)src/*", "src/foo.c src/x.go"},
+		{":(glob)src/**/z.*", "src/a/b/z.go src/a/b/z.txt"},
+		{"src/a/*.go", "src/a/b/z.go src/a/y.go"},
+		{":(exclude)vendor", "README.md docs/Guide.MD foo.c main.go src/a/b/z.go src/a/b/z.txt src/a/y.go src/foo.c src/x.go"},
+		{":!*.go", "README.md docs/Guide.MD foo.c src/a/b/z.txt src/foo.c"},
+		{":^src", "README.md docs/Guide.MD foo.c main.go vendor/x/v.go"},
+		{":(literal)*.go", ""},
+		{":(icase)docs/guide.md", "docs/Guide.MD"},
+		{":(glob,icase)DOCS/*.md", "docs/Guide.MD"},
+		{"s?c/x.go", "src/x.go"},
+		{"[sv]*/x*", "src/x.go vendor/x/v.go"},
+		{":(glob)src/[a-b]/*", "src/a/y.go"},
+		{"foo.c", "foo.c"},
+		{":(top)src/a", "src/a/b/z.go src/a/b/z.txt src/a/y.go"},
+		{":/vendor", "vendor/x/v.go"},
+	}
+	for _, tt := range tests {
+		filtered, err := pathspecTestDiff().Filter(tt.spec)
+		if err != nil {
+			t.Fatalf("Filter(%q) returned error: %v", tt.spec, err)
+		}
+		if got := filePaths(filtered); got != tt.want {
+			t.Errorf("Filter(%q) = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestFilterIncludeAndExclude(t *testing.T) {
+	filtered, err := pathspecTestDiff().Filter("*.go", ":(exclude)vendor", ":!src/a/b")
+	if err != nil {
+		t.Fatalf("Filter returned error: %v", err)
+	}
+	if got, want := filePaths(filtered), "main.go src/a/y.go src/x.go"; got != want {
+		t.Errorf("Filter = %q, want %q", got, want)
+	}
+}
+
+func TestFilterRenameMatchesEitherPath(t *testing.T) {
+	diff := &Diff{Files: []*FileDiff{
+		{Status: FileStatusRenamed, OldPath: "old/a.txt", NewPath: "new/a.txt"},
+	}}
+	for _, spec := range []string{"old", "new"} {
+		filtered, err := diff.Filter(spec)
+		if err != nil {
+			t.Fatalf("Filter returned error: %v", err)
+		}
+		if len(filtered.Files) != 1 {
+			t.Errorf("expected %s to match the rename", spec)
+		}
+	}
+}
+
+func TestFilterInvalidPathspec(t *testing.T) {
+	for _, spec := range []string{":(nope)x", ":(glob,literal)x", ":(glob"} {
+		if _, err := pathspecTestDiff().Filter(spec); err == nil {
+			t.Errorf("expected error for %q, got nil", spec)
+		}
+	}
+}
+
+func TestWildmatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		pathname      bool
+		want          bool
+	}{
+		{"a/**/b", "a/b", true, true},
+		{"a/**/b", "a/x/y/b", true, true},
+		{"a/**", "a", true, false},
+		{"**", "a/b/c", true, true},
+		{"a**b", "a/b", true, false},
+		{"a*b", "a/b", false, true},
+		{"[!a]x", "bx", true, true},
+		{"[!a]x", "ax", true, false},
+		{"[]]", "]", true, true},
+		{"\\*", "*", true, true},
+		{"\\*", "a", true, false},
+		{"[a", "a", true, false},
+	}
+	for _, tt := range tests {
+		if got := wildmatch(tt.pattern, tt.name, tt.pathname); got != tt.want {
+			t.Errorf("wildmatch(%q, %q, %v) = %v, want %v", tt.pattern, tt.name, tt.pathname, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package godiffy
+
+import "sort"
+
+const (
+	patienceNoMatch   = -1
+	patienceNonUnique = -2
+)
+
+type patienceEntry struct {
+	line1, line2 int // line2 is patienceNoMatch or patienceNonUnique unless the line is unique in both
+	prev, next   *patienceEntry
+}
+
+type patience struct {
+	a, b               []int
+	changed1, changed2 []bool
+}
+
+// patienceDiff marks the changed lines of a and b with the patience
+// algorithm: lines that occur exactly once on both sides anchor the diff,
+// the longest increasing run of them is kept and the gaps in between are
+// diffed recursively. Gaps without unique lines fall back to Myers.
+func patienceDiff(a, b []int) (changed1, changed2 []bool) {
+	p := &patience{
+		a:        a,
+		b:        b,
+		changed1: make([]bool, len(a)),
+		changed2: make([]bool, len(b)),
+	}
+	p.diff(0, len(a), 0, len(b))
+	return p.changed1, p.changed2
+}
+
+func (p *patience) diff(line1, count1, line2, count2 int) {
+	if count1 == 0 {
+		markChanged(p.changed2, line2, count2)
+		return
+	}
+	if count2 == 0 {
+		markChanged(p.changed1, line1, count1)
+		return
+	}
+
+	entries, hasMatches := p.uniqueLines(line1, count1, line2, count2)
+	if !hasMatches {
+		markChanged(p.changed1, line1, count1)
+		markChanged(p.changed2, line2, count2)
+		return
+	}
+
+	if first := longestCommonSequence(entries); first != nil {
+		p.walk(first, line1, count1, line2, count2)
+		return
+	}
+	changed1, changed2 := myersDiff(p.a[line1:line1+count1], p.b[line2:line2+count2])
+	copy(p.changed1[line1:], changed1)
+	copy(p.changed2[line2:], changed2)
+}
+
+// uniqueLines lists the distinct lines of the first range in order of
+// appearance and records where they match in the second range.
+func (p *patience) uniqueLines(line1, count1, line2, count2 int) (entries []*patienceEntry, hasMatches bool) {
+	byID := make(map[int]*patienceEntry, count1)
+	for i := line1; i < line1+count1; i++ {
+		if entry, ok := byID[p.a[i]]; ok {
+			entry.line2 = patienceNonUnique
+			continue
+		}
+		entry := &patienceEntry{line1: i, line2: patienceNoMatch}
+		byID[p.a[i]] = entry
+		entries = append(entries, entry)
+	}
+	for i := line2; i < line2+count2; i++ {
+		entry, ok := byID[p.b[i]]
+		if !ok {
+			continue
+		}
+		hasMatches = true
+		if entry.line2 == patienceNoMatch {
+			entry.line2 = i
+		} else {
+			entry.line2 = patienceNonUnique
+		}
+	}
+	return entries, hasMatches
+}
+
+// longestCommonSequence runs patience sorting over the unique lines and
+// returns the first entry of the longest chain, linked through next.
+func longestCommonSequence(entries []*patienceEntry) *patienceEntry {
+	var sequence []*patienceEntry
+	for _, entry := range entries {
+		if entry.line2 < 0 {
+			continue
+		}
+		i := sort.Search(len(sequence), func(i int) bool {
+			return sequence[i].line2 > entry.line2
+		}) - 1
+		entry.prev = nil
+		if i >= 0 {
+			entry.prev = sequence[i]
+		}
+		i++
+		if i == len(sequence) {
+			sequence = append(sequence, entry)
+		} else {
+			sequence[i] = entry
+		}
+	}
+	if len(sequence) == 0 {
+		return nil
+	}
+
+	entry := sequence[len(sequence)-1]
+	entry.next = nil
+	for entry.prev != nil {
+		entry.prev.next = entry
+		entry = entry.prev
+	}
+	return entry
+}
+
+func (p *patience) walk(first *patienceEntry, line1, count1, line2, count2 int) {
+	end1, end2 := line1+count1, line2+count2
+	for {
+		next1, next2 := end1, end2
+		if first != nil {
+			next1, next2 = first.line1, first.line2
+			for next1 > line1 && next2 > line2 && p.a[next1-1] == p.b[next2-1] {
+				next1--
+				next2--
+			}
+		}
+		for line1 < next1 && line2 < next2 && p.a[line1] == p.b[line2] {
+			line1++
+			line2++
+		}
+
+		if next1 > line1 || next2 > line2 {
+			p.diff(line1, next1-line1, line2, next2-line2)
+		}
+		if first == nil {
+			return
+		}
+
+		for first.next != nil && first.next.line1 == first.line1+1 && first.next.line2 == first.line2+1 {
+			first = first.next
+		}
+		line1, line2 = first.line1+1, first.line2+1
+		first = first.next
+	}
+}
+
+func markChanged(changed []bool, start, count int) {
+	for i := start; i < start+count; i++ {
+		changed[i] = true
+	}
+}
@@ -0,0 +1,121 @@
+package godiffy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cEscapes maps the bytes git writes as a letter escape in quoted paths.
+var cEscapes = map[byte]byte{
+	'\a': 'a',
+	'\b': 'b',
+	'\t': 't',
+	'\n': 'n',
+	'\v': 'v',
+	'\f': 'f',
+	'\r': 'r',
+	'"':  '"',
+	'\\': '\\',
+}
+
+// needsQuoting reports whether git quotes the path, which it does for
+// control characters, quotes, backslashes and, with the default
+// core.quotePath, every byte outside of ASCII.
+func needsQuoting(path string) bool {
+	for i := 0; i < len(path); i++ {
+		if c := path[i]; c < 0x20 || c == '"' || c == '\\' || c >= 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// quotePath returns the path the way git prints it: unchanged, or in
+// double quotes with C-style escapes, as in "t\303\244st.txt".
+func quotePath(path string) string {
+	return quotePrefixed("", path)
+}
+
+// quotePrefixed quotes a prefix and a path as one, as git does for the
+// "a/" and "b/" sides of headers.
+func quotePrefixed(prefix, path string) string {
+	if !needsQuoting(prefix + path) {
+		return prefix + path
+	}
+	s := prefix + path
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch esc, ok := cEscapes[c]; {
+		case ok:
+			b.WriteByte('\\')
+			b.WriteByte(esc)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// unquotePath undoes quotePath. Paths that do not start with a quote are
+// returned as they are.
+func unquotePath(s string) (string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return s, nil
+	}
+	path, rest, err := cutQuoted(s)
+	if err != nil {
+		return "", err
+	}
+	if rest != "" {
+		return "", fmt.Errorf("invalid quoted path: %s", s)
+	}
+	return path, nil
+}
+
+// cutQuoted reads the quoted path at the start of s and returns it
+// unescaped, along with what follows the closing quote.
+func cutQuoted(s string) (path, rest string, err error) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", "", fmt.Errorf("invalid quoted path: %s", s)
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			return b.String(), s[i+1:], nil
+		case c != '\\':
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(s) {
+			break
+		}
+		if c := s[i]; c >= '0' && c <= '3' {
+			if i+2 >= len(s) || s[i+1] < '0' || s[i+1] > '7' || s[i+2] < '0' || s[i+2] > '7' {
+				return "", "", fmt.Errorf("invalid escape in quoted path: %s", s)
+			}
+			b.WriteByte((c-'0')<<6 | (s[i+1]-'0')<<3 | (s[i+2] - '0'))
+			i += 2
+			continue
+		}
+		unescaped := false
+		for raw, esc := range cEscapes {
+			if esc == s[i] {
+				b.WriteByte(raw)
+				unescaped = true
+				break
+			}
+		}
+		if !unescaped {
+			return "", "", fmt.Errorf("invalid escape in quoted path: %s", s)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted path: %s", s)
+}
@@ -0,0 +1,139 @@
+package godiffy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQuotePath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"plain.txt", "plain.txt"},
+		{"x y", "x y"},
+		{"täst.txt", `"t\303\244st.txt"`},
+		{"ta\tb\"q", `"ta\tb\"q"`},
+		{"back\\slash", `"back\\slash"`},
+		{"line\nbreak\r", `"line\nbreak\r"`},
+		{"\x01\x7f", `"\001\177"`},
+	}
+	for _, tt := range tests {
+		got := quotePath(tt.path)
+		if got != tt.want {
+			t.Errorf("quotePath(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+		back, err := unquotePath(got)
+		if err != nil || back != tt.path {
+			t.Errorf("unquotePath(%s) = %q, %v, want %q", got, back, err, tt.path)
+		}
+	}
+	if got := quotePrefixed("a/", "täst"); got != `"a/t\303\244st"` {
+		t.Errorf("quotePrefixed() = %s", got)
+	}
+}
+
+func TestUnquotePathErrors(t *testing.T) {
+	for _, s := range []string{`"open`, `"bad\q"`, `"short\30"`, `"a" b`, `"end\`} {
+		if got, err := unquotePath(s); err == nil {
+			t.Errorf("unquotePath(%s) = %q, want an error", s, got)
+		}
+	}
+}
+
+// The fixtures come from git with the default core.quotePath on files
+// named with spaces, tabs, quotes and non-ASCII letters.
+func TestParseQuotedPaths(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "quote", "input.diff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := Parse(string(input))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	want := [][2]string{
+		{"bin ä", "bin ä"},
+		{"mv me", "moved ö"},
+		{"new file", "new file"},
+		{"ta\tb\"q", "ta\tb\"q"},
+		{"täst.txt", "täst.txt"},
+		{"x y", "x y"},
+	}
+	if len(diff.Files) != len(want) {
+		t.Fatalf("got %d files, want %d", len(diff.Files), len(want))
+	}
+	for i, file := range diff.Files {
+		if file.OldPath != want[i][0] || file.NewPath != want[i][1] {
+			t.Errorf("file %d: got %q -> %q, want %q -> %q", i, file.OldPath, file.NewPath, want[i][0], want[i][1])
+		}
+	}
+	if got := diff.String(); got != string(input) {
+		t.Errorf("String() =\n%s\nwant\n%s", got, input)
+	}
+
+	stat := diff.Stat()
+	stat.Files[0].Deleted, stat.Files[0].Added = 2, 1
+	for _, tt := range []struct{ fixture, got string }{
+		{"stat.txt", stat.String()},
+		{"numstat.txt", stat.Numstat(false)},
+	} {
+		want, err := os.ReadFile(filepath.Join("testdata", "quote", tt.fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.got != string(want) {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.fixture, tt.got, want)
+		}
+	}
+
+	raw, err := os.ReadFile(filepath.Join("testdata", "quote", "raw.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseRaw(string(raw))
+	if err != nil {
+		t.Fatalf("ParseRaw returned error: %v", err)
+	}
+	for i, file := range parsed.Files {
+		if file.OldPath != want[i][0] || file.NewPath != want[i][1] {
+			t.Errorf("raw file %d: got %q -> %q, want %q -> %q", i, file.OldPath, file.NewPath, want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestParseHeaderPaths(t *testing.T) {
+	tests := []struct {
+		line, oldPath, newPath string
+	}{
+		{"diff --git a/x y b/x y\n", "x y", "x y"},
+		{"diff --git a/a b/c b/a b/c\n", "a b/c", "a b/c"},
+		{`diff --git "a/t\303\244st" "b/t\303\244st"` + "\n", "täst", "täst"},
+		{`diff --git a/mv me "b/moved \303\266"` + "\n", "mv me", "moved ö"},
+		{`diff --git "a/moved \303\266" b/mv me` + "\n", "moved ö", "mv me"},
+		{`diff --git a/say "hi" "b/say \"hi\""` + "\n", `say "hi"`, `say "hi"`},
+	}
+	for _, tt := range tests {
+		oldRaw, newRaw, prefixes := parseHeaderPaths(tt.line, DefaultParseOptions(), [2]string{"a/", "b/"})
+		oldPath, newPath := strings.TrimPrefix(oldRaw, prefixes[0]), strings.TrimPrefix(newRaw, prefixes[1])
+		if oldPath != tt.oldPath || newPath != tt.newPath {
+			t.Errorf("parseHeaderPaths(%q) = %q, %q, want %q, %q", tt.line, oldPath, newPath, tt.oldPath, tt.newPath)
+		}
+	}
+}
+
+func TestParseRenameWithSpaces(t *testing.T) {
+	input := "diff --git a/old name.txt b/new name.txt\nsimilarity index 100%\nrename from old name.txt\nrename to new name.txt\n"
+	diff, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	file := diff.Files[0]
+	if file.OldName != "old name.txt" || file.NewName != "new name.txt" || file.OldPath != "old name.txt" || file.NewPath != "new name.txt" {
+		t.Errorf("got %+v", file)
+	}
+	if got := diff.String(); got != input {
+		t.Errorf("String() = %q, want %q", got, input)
+	}
+}
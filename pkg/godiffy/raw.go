@@ -0,0 +1,294 @@
+package godiffy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// nullMode is the mode git prints for the missing side of an added or
+// deleted file.
+const nullMode = "000000"
+
+// ParseRaw reads the output of "git diff --raw", with or without -z, into
+// file diffs without hunks. Modes and hashes are kept as git printed them,
+// abbreviated or not.
+//
+//	:100644 100644 bcd1234 0123456 M	file0
+//	:100644 100644 abcd123 1234567 R86	file1	file3
+func ParseRaw(input string) (*Diff, error) {
+	diff := &Diff{}
+	fields, err := splitSummaryFields(input)
+	if err != nil {
+		return nil, err
+	}
+	for len(fields) > 0 {
+		meta := fields[0]
+		if !strings.HasPrefix(meta, ":") || strings.HasPrefix(meta, "::") {
+			return nil, fmt.Errorf("invalid raw line: %s", meta)
+		}
+		parts := strings.Fields(meta[1:]) // ["100644","100644","bcd1234","0123456","M"]
+		if len(parts) != 5 {
+			return nil, fmt.Errorf("invalid raw line: %s", meta)
+		}
+		file, paths, err := newSummaryFileDiff(parts[4], fields[1:])
+		if err != nil {
+			return nil, err
+		}
+		fields = fields[1+paths:]
+
+		var oldMode, newMode FileMode
+		if parts[0] != nullMode {
+			oldMode, err = ParseFileMode(parts[0])
+			if err != nil {
+				return nil, err
+			}
+		}
+		if parts[1] != nullMode {
+			newMode, err = ParseFileMode(parts[1])
+			if err != nil {
+				return nil, err
+			}
+		}
+		file.OldHash, err = ParseObjectID(parts[2])
+		if err != nil {
+			return nil, err
+		}
+		file.NewHash, err = ParseObjectID(parts[3])
+		if err != nil {
+			return nil, err
+		}
+		if oldMode.IsGitlink() || newMode.IsGitlink() {
+			file.Submodule = &SubmoduleChange{}
+			if oldMode.IsGitlink() {
+				file.Submodule.OldCommit = file.OldHash
+			}
+			if newMode.IsGitlink() {
+				file.Submodule.NewCommit = file.NewHash
+			}
+		}
+		// Like Parse, an unchanged mode is only kept on the new side.
+		if oldMode == newMode && file.Status != FileStatusNew && file.Status != FileStatusDeleted {
+			oldMode = ""
+		}
+		file.OldMode, file.NewMode = oldMode, newMode
+		diff.Files = append(diff.Files, file)
+	}
+	return diff, nil
+}
+
+// ParseNameStatus reads the output of "git diff --name-status", with or
+// without -z, into file diffs that carry only paths and statuses.
+func ParseNameStatus(input string) (*Diff, error) {
+	diff := &Diff{}
+	fields, err := splitSummaryFields(input)
+	if err != nil {
+		return nil, err
+	}
+	for len(fields) > 0 {
+		file, paths, err := newSummaryFileDiff(fields[0], fields[1:])
+		if err != nil {
+			return nil, err
+		}
+		fields = fields[1+paths:]
+		diff.Files = append(diff.Files, file)
+	}
+	return diff, nil
+}
+
+// ParseNumstat reads the output of "git diff --numstat", with or without
+// -z. Without -z renames are only given as "dir/{old => new}", which is
+// split back into the two paths; use -z for paths that contain " => ".
+func ParseNumstat(input string) (*DiffStat, error) {
+	stat := &DiffStat{}
+	nulTerminated := strings.Contains(input, "\x00")
+	var records []string
+	if nulTerminated {
+		records = strings.Split(strings.TrimSuffix(input, "\x00"), "\x00")
+	} else {
+		records = strings.Split(strings.TrimSuffix(input, "\n"), "\n")
+	}
+	if input == "" {
+		records = nil
+	}
+	for len(records) > 0 {
+		parts := strings.SplitN(records[0], "\t", 3) // ["1","2","path"]
+		records = records[1:]
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid numstat line: %s", parts[0])
+		}
+		file := &FileStat{}
+		if parts[0] == "-" && parts[1] == "-" {
+			file.IsBinary = true
+		} else {
+			var err error
+			file.Added, err = strconv.Atoi(parts[0])
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse added lines %s: %w", parts[0], err)
+			}
+			file.Deleted, err = strconv.Atoi(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse deleted lines %s: %w", parts[1], err)
+			}
+		}
+
+		switch {
+		case nulTerminated && parts[2] == "": // renames: "1\t0\t\0old\0new\0"
+			if len(records) < 2 {
+				return nil, fmt.Errorf("missing paths for renamed file in numstat")
+			}
+			file.OldPath, file.NewPath = records[0], records[1]
+			records = records[2:]
+		case nulTerminated:
+			file.OldPath, file.NewPath = parts[2], parts[2]
+		default:
+			var err error
+			file.OldPath, file.NewPath, err = splitRenameName(parts[2])
+			if err != nil {
+				return nil, err
+			}
+		}
+		stat.Files = append(stat.Files, file)
+		if !file.IsBinary {
+			stat.Added += file.Added
+			stat.Deleted += file.Deleted
+		}
+	}
+	return stat, nil
+}
+
+// splitSummaryFields splits --raw and --name-status output into its fields,
+// which -z separates by NUL and the plain format by tabs and newlines. Only
+// the plain format quotes paths.
+func splitSummaryFields(input string) ([]string, error) {
+	if input == "" {
+		return nil, nil
+	}
+	if strings.Contains(input, "\x00") {
+		return strings.Split(strings.TrimSuffix(input, "\x00"), "\x00"), nil
+	}
+	var fields []string
+	for line := range strings.Lines(input) {
+		for field := range strings.SplitSeq(strings.TrimSuffix(line, "\n"), "\t") {
+			field, err := unquotePath(field)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// newSummaryFileDiff builds a file diff from a status like "M" or "R086"
+// and the paths that follow it. It returns how many paths it used.
+func newSummaryFileDiff(status string, paths []string) (*FileDiff, int, error) {
+	if status == "" {
+		return nil, 0, fmt.Errorf("missing file status")
+	}
+	file := &FileDiff{}
+	score := status[1:]
+	if score != "" {
+		if n, err := strconv.Atoi(score); err != nil || n < 0 || n > 100 {
+			return nil, 0, fmt.Errorf("invalid status score: %s", status)
+		}
+	}
+	switch status[0] {
+	case 'A':
+		file.Status = FileStatusNew
+	case 'D':
+		file.Status = FileStatusDeleted
+	case 'M':
+		file.Status = FileStatusModified
+	case 'R':
+		file.Status = FileStatusRenamed
+	case 'C':
+		file.Status = FileStatusCopied
+	case 'T':
+		file.Status = FileStatusTypeChanged
+	case 'U':
+		file.Status = FileStatusUnmerged
+	case 'X':
+		file.Status = FileStatusUnknown
+	default:
+		return nil, 0, fmt.Errorf("invalid file status: %s", status)
+	}
+
+	used := 1
+	if file.Status == FileStatusRenamed || file.Status == FileStatusCopied {
+		used = 2
+		if score != "" {
+			// Scores without leading zeros, as in git's similarity index.
+			n, _ := strconv.Atoi(score)
+			file.SimilarityIndex = strconv.Itoa(n) + "%"
+		}
+	}
+	if len(paths) < used {
+		return nil, 0, fmt.Errorf("missing path for file status %s", status)
+	}
+	file.OldPath, file.NewPath = paths[0], paths[used-1]
+	if used == 2 {
+		file.OldName, file.NewName = file.OldPath, file.NewPath
+	}
+	file.Header = file.headerLine()
+	return file, used, nil
+}
+
+// splitRenameName undoes FileStat.Name, turning "dir/{a => b}/f" back into
+// "dir/a/f" and "dir/b/f", and unquoting quoted paths.
+func splitRenameName(name string) (oldPath, newPath string, err error) {
+	if strings.HasPrefix(name, `"`) || strings.HasSuffix(name, `"`) {
+		return splitQuotedRenameName(name)
+	}
+	open, arrow := strings.IndexByte(name, '{'), strings.Index(name, " => ")
+	if arrow == -1 {
+		return name, name, nil
+	}
+	end := strings.IndexByte(name[arrow:], '}')
+	if open == -1 || open > arrow || end == -1 {
+		return name[:arrow], name[arrow+4:], nil
+	}
+	end += arrow
+	prefix, suffix := name[:open], name[end+1:]
+	join := func(mid string) string {
+		// "dir/{ => sub}/f" has an empty side that must not leave "dir//f".
+		if mid == "" && strings.HasSuffix(prefix, "/") && strings.HasPrefix(suffix, "/") {
+			return prefix + suffix[1:]
+		}
+		if mid == "" && prefix == "" {
+			return strings.TrimPrefix(suffix, "/")
+		}
+		return prefix + mid + suffix
+	}
+	return join(name[open+1 : arrow]), join(name[arrow+4 : end]), nil
+}
+
+// splitQuotedRenameName splits names like "a b" => "t\303\244st", where
+// either side may be quoted and neither uses braces.
+func splitQuotedRenameName(name string) (oldPath, newPath string, err error) {
+	rest := name
+	if strings.HasPrefix(name, `"`) {
+		oldPath, rest, err = cutQuoted(name)
+		if err != nil {
+			return "", "", err
+		}
+		if rest == "" {
+			return oldPath, oldPath, nil
+		}
+		if !strings.HasPrefix(rest, " => ") {
+			return "", "", fmt.Errorf("invalid rename name: %s", name)
+		}
+		rest = rest[4:]
+	} else {
+		arrow := strings.LastIndex(name, ` => "`)
+		if arrow == -1 {
+			return "", "", fmt.Errorf("invalid rename name: %s", name)
+		}
+		oldPath, rest = name[:arrow], name[arrow+4:]
+	}
+	newPath, err = unquotePath(rest)
+	if err != nil {
+		return "", "", err
+	}
+	return oldPath, newPath, nil
+}
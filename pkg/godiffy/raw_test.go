@@ -0,0 +1,150 @@
+package godiffy
+
+import (
+	"strings"
+	"testing"
+)
+
+// From "git diff --cached -C --find-copies-harder --raw" and a merge
+// conflict.
+const testRaw = `:100644 000000 01e79c3 0000000 D	del.txt
+:100644 100755 28ce6a8 28ce6a8 M	mode.sh
+:000000 100644 0000000 3e75765 A	new.txt
+:100644 100644 aa5e3f8 4bdc208 M	src/big.txt
+:100644 100644 e8823e1 10adcaf R096	src/lib/deep/mv.txt	src/other/deep/mv.txt
+:100644 100644 e8823e1 e8823e1 C100	orig.txt	copy of.txt
+:100644 120000 587be6b bf54367 T	link
+:000000 100644 0000000 0000000 U	conf.txt
+`
+
+func rawFilesString(diff *Diff) string {
+	var b strings.Builder
+	for _, f := range diff.Files {
+		b.WriteString(strings.Join([]string{
+			statusLetter(f.Status), f.SimilarityIndex, f.OldPath, f.NewPath, string(f.OldMode), string(f.NewMode), string(f.OldHash), string(f.NewHash),
+		}, "|") + "\n")
+	}
+	return b.String()
+}
+
+func statusLetter(status FileStatus) string {
+	return string("ADMRCXTU"[status])
+}
+
+func TestParseRaw(t *testing.T) {
+	want := `D||del.txt|del.txt|100644||01e79c3|0000000
+M||mode.sh|mode.sh|100644|100755|28ce6a8|28ce6a8
+A||new.txt|new.txt||100644|0000000|3e75765
+M||src/big.txt|src/big.txt||100644|aa5e3f8|4bdc208
+R|96%|src/lib/deep/mv.txt|src/other/deep/mv.txt||100644|e8823e1|10adcaf
+C|100%|orig.txt|copy of.txt||100644|e8823e1|e8823e1
+T||link|link|100644|120000|587be6b|bf54367
+U||conf.txt|conf.txt||100644|0000000|0000000
+`
+	diff, err := ParseRaw(testRaw)
+	if err != nil {
+		t.Fatalf("ParseRaw returned error: %v", err)
+	}
+	if got := rawFilesString(diff); got != want {
+		t.Errorf("ParseRaw() =\n%s\nwant\n%s", got, want)
+	}
+
+	z := strings.NewReplacer("\t", "\x00", "\n", "\x00").Replace(testRaw)
+	diff, err = ParseRaw(z)
+	if err != nil {
+		t.Fatalf("ParseRaw with -z returned error: %v", err)
+	}
+	if got := rawFilesString(diff); got != want {
+		t.Errorf("ParseRaw() with -z =\n%s\nwant\n%s", got, want)
+	}
+
+	renamed := diff.Files[4]
+	if renamed.OldName != "src/lib/deep/mv.txt" || renamed.NewName != "src/other/deep/mv.txt" {
+		t.Errorf("rename names: got %q -> %q", renamed.OldName, renamed.NewName)
+	}
+	if got, want := renamed.String(), "diff --git a/src/lib/deep/mv.txt b/src/other/deep/mv.txt\nsimilarity index 96%\n"+
+		"rename from src/lib/deep/mv.txt\nrename to src/other/deep/mv.txt\nindex e8823e1..10adcaf 100644\n"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestParseNameStatus(t *testing.T) {
+	input := "D\tdel.txt\nM\tmode.sh\nR096\tsrc/lib/deep/mv.txt\tsrc/other/deep/mv.txt\nA\tnew.txt\n"
+	want := `D||del.txt|del.txt||||
+M||mode.sh|mode.sh||||
+R|96%|src/lib/deep/mv.txt|src/other/deep/mv.txt||||
+A||new.txt|new.txt||||
+`
+	for _, in := range []string{input, strings.NewReplacer("\t", "\x00", "\n", "\x00").Replace(input)} {
+		diff, err := ParseNameStatus(in)
+		if err != nil {
+			t.Fatalf("ParseNameStatus(%q) returned error: %v", in, err)
+		}
+		if got := rawFilesString(diff); got != want {
+			t.Errorf("ParseNameStatus(%q) =\n%s\nwant\n%s", in, got, want)
+		}
+	}
+}
+
+func TestParseNumstat(t *testing.T) {
+	stat := gitStat(t)
+	for _, nulTerminated := range []bool{false, true} {
+		input := stat.Numstat(nulTerminated)
+		parsed, err := ParseNumstat(input)
+		if err != nil {
+			t.Fatalf("ParseNumstat(%q) returned error: %v", input, err)
+		}
+		if got := parsed.Numstat(nulTerminated); got != input {
+			t.Errorf("round trip: got %q, want %q", got, input)
+		}
+		if parsed.Added != 117 || parsed.Deleted != 115 {
+			t.Errorf("totals: got +%d -%d, want +117 -115", parsed.Added, parsed.Deleted)
+		}
+		renamed := parsed.Files[7]
+		if renamed.OldPath != "src/lib/deep/mv.txt" || renamed.NewPath != "src/other/deep/mv.txt" {
+			t.Errorf("rename: got %q -> %q", renamed.OldPath, renamed.NewPath)
+		}
+		if !parsed.Files[2].IsBinary {
+			t.Errorf("img.bin is not marked binary")
+		}
+	}
+}
+
+func TestSplitRenameName(t *testing.T) {
+	for _, tt := range []struct{ oldPath, newPath string }{
+		{"a.txt", "b.txt"},
+		{"src/a.go", "src/b.go"},
+		{"a/x.go", "b/x.go"},
+		{"dir/x.go", "dir/sub/x.go"},
+		{"dir/sub/x.go", "dir/x.go"},
+		{"x.go", "sub/x.go"},
+		{"orig.txt", "copy of.txt"},
+		{"mv me", "moved \u00f6"},
+		{"t\u00e4st", "x y"},
+		{"t\u00e4st", "t\u00e4st 2"},
+	} {
+		name := (&FileStat{OldPath: tt.oldPath, NewPath: tt.newPath}).Name()
+		if oldPath, newPath, err := splitRenameName(name); err != nil || oldPath != tt.oldPath || newPath != tt.newPath {
+			t.Errorf("splitRenameName(%q) = %q, %q, want %q, %q", name, oldPath, newPath, tt.oldPath, tt.newPath)
+		}
+	}
+}
+
+func TestParseSummaryErrors(t *testing.T) {
+	for _, input := range []string{
+		":100644 100644 abc def\tfile\n",
+		":100644 100644 abc def Q\tfile\n",
+		":100644 100644 abc def R1x\told\tnew\n",
+		":100644 100644 abc def R100\told\n",
+		"::100644 100644 100644 abc def ghi MM\tfile\n",
+	} {
+		if _, err := ParseRaw(input); err == nil {
+			t.Errorf("ParseRaw(%q) returned no error", input)
+		}
+	}
+	for _, input := range []string{"x\t1\tfile\n", "1\tfile\n"} {
+		if _, err := ParseNumstat(input); err == nil {
+			t.Errorf("ParseNumstat(%q) returned no error", input)
+		}
+	}
+}
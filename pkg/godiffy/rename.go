@@ -0,0 +1,131 @@
+package godiffy
+
+import (
+	"cmp"
+	"path"
+	"slices"
+)
+
+const (
+	DefaultRenameThreshold = 50
+
+	maxSimilarityScore = 60000  // git's MAX_SCORE
+	spanHashBase       = 107927 // git's HASHBASE
+	spanMaxLength      = 64
+)
+
+// spanCounts splits data into spans ending at a newline or after 64 bytes
+// and sums up the span lengths per span hash, like git's diffcore-delta.
+func spanCounts(data []byte) map[uint32]int {
+	counts := make(map[uint32]int)
+	text := !isBinary(data)
+	var accum1, accum2 uint32
+	n := 0
+	for i := 0; i < len(data); i++ {
+		c := uint32(data[i])
+		if text && c == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			continue
+		}
+		old1 := accum1
+		accum1 = (accum1 << 7) ^ (accum2 >> 25)
+		accum2 = (accum2 << 7) ^ (old1 >> 25)
+		accum1 += c
+		n++
+		if n < spanMaxLength && c != '\n' {
+			continue
+		}
+		counts[(accum1+accum2*0x61)%spanHashBase] += n
+		n, accum1, accum2 = 0, 0, 0
+	}
+	if n > 0 {
+		counts[(accum1+accum2*0x61)%spanHashBase] += n
+	}
+	return counts
+}
+
+// similarityScore estimates how much of dst was copied from src on git's
+// 0..60000 scale. Pairs whose sizes differ too much to reach minScore are
+// rejected without looking at the content.
+func similarityScore(src, dst *treeFile, minScore int) int {
+	maxSize := max(len(src.data), len(dst.data))
+	baseSize := min(len(src.data), len(dst.data))
+	if maxSize*(maxSimilarityScore-minScore) < (maxSize-baseSize)*maxSimilarityScore {
+		return 0
+	}
+	if len(dst.data) == 0 {
+		return 0
+	}
+
+	if src.spans == nil {
+		src.spans = spanCounts(src.data)
+	}
+	if dst.spans == nil {
+		dst.spans = spanCounts(dst.data)
+	}
+	copied := 0
+	for hash, srcCount := range src.spans {
+		copied += min(srcCount, dst.spans[hash])
+	}
+	return copied * maxSimilarityScore / maxSize
+}
+
+type renameCandidate struct {
+	src, dst int
+	score    int
+}
+
+// detectRenames pairs added files with removed ones, and with modified ones
+// when copies are wanted. Identical content is paired first, then the most
+// similar files above the threshold. It returns the source index for each
+// destination, or -1.
+func detectRenames(sources []*treeFile, deleted []bool, dests []*treeFile, opts *TreeDiffOptions) (match []int, scores []int) {
+	match = make([]int, len(dests))
+	scores = make([]int, len(dests))
+	used := make([]bool, len(sources))
+	for i := range match {
+		match[i] = -1
+	}
+
+	for d, dst := range dests {
+		best := -1
+		for s, src := range sources {
+			if used[s] || !deleted[s] || src.hash != dst.hash {
+				continue
+			}
+			if best == -1 || (path.Base(src.path) == path.Base(dst.path) && path.Base(sources[best].path) != path.Base(dst.path)) {
+				best = s
+			}
+		}
+		if best != -1 {
+			match[d], scores[d] = best, maxSimilarityScore
+			used[best] = true
+		}
+	}
+
+	minScore := opts.RenameThreshold * maxSimilarityScore / 100
+	var candidates []renameCandidate
+	for d, dst := range dests {
+		if match[d] != -1 || !dst.isRegular() {
+			continue
+		}
+		for s, src := range sources {
+			if (used[s] && !opts.DetectCopies) || !src.isRegular() {
+				continue
+			}
+			if score := similarityScore(src, dst, minScore); score >= minScore {
+				candidates = append(candidates, renameCandidate{src: s, dst: d, score: score})
+			}
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b renameCandidate) int {
+		return cmp.Compare(b.score, a.score)
+	})
+	for _, c := range candidates {
+		if match[c.dst] != -1 || (used[c.src] && !opts.DetectCopies) {
+			continue
+		}
+		match[c.dst], scores[c.dst] = c.src, c.score
+		used[c.src] = true
+	}
+	return match, scores
+}
@@ -0,0 +1,103 @@
+package godiffy
+
+import "strings"
+
+// Reverse returns a diff that undoes d, like "git apply -R" reads it. The
+// receiver is not modified.
+func (d *Diff) Reverse() *Diff {
+	result := &Diff{Files: make([]*FileDiff, 0, len(d.Files))}
+	for _, file := range d.Files {
+		result.Files = append(result.Files, file.Reverse())
+	}
+	// A change of type is a deletion followed by a creation of the same
+	// path, which must stay in that order when reversed.
+	files := result.Files
+	for i := 0; i+1 < len(files); i++ {
+		if files[i].Status == FileStatusNew && files[i+1].Status == FileStatusDeleted && files[i].NewPath == files[i+1].NewPath {
+			files[i], files[i+1] = files[i+1], files[i]
+			i++
+		}
+	}
+	return result
+}
+
+// Reverse returns a FileDiff that undoes f. New files become deletions and
+// the other way round, and renames point back at their source. A copy
+// becomes a deletion of the copy that keeps its reversed hunks, so
+// reversing it twice gives a new file rather than the copy.
+func (f *FileDiff) Reverse() *FileDiff {
+	reversed := &FileDiff{
+		OldHash:            f.NewHash,
+		NewHash:            f.OldHash,
+		SimilarityIndex:    f.SimilarityIndex,
+		DissimilarityIndex: f.DissimilarityIndex,
+		OldPath:            f.NewPath,
+		NewPath:            f.OldPath,
+		OldName:            f.NewName,
+		NewName:            f.OldName,
+		OldMode:            f.NewMode,
+		NewMode:            f.OldMode,
+		Status:             f.Status,
+		IsBinary:           f.IsBinary,
+		Submodule:          f.Submodule.reverse(),
+	}
+	switch f.Status {
+	case FileStatusNew:
+		reversed.Status = FileStatusDeleted
+	case FileStatusDeleted:
+		reversed.Status = FileStatusNew
+	}
+	// The index line only carries the mode of an unchanged file, as the new
+	// mode, so it stays on the new side.
+	if f.OldMode == "" && f.Status != FileStatusNew && f.Status != FileStatusDeleted {
+		reversed.OldMode, reversed.NewMode = "", f.NewMode
+	}
+	// git apply -R refuses a copy, as its source exists; undoing it only
+	// takes removing the copy.
+	if f.Status == FileStatusCopied {
+		reversed.Status = FileStatusDeleted
+		reversed.OldPath, reversed.NewPath = f.NewPath, f.NewPath
+		reversed.OldName, reversed.NewName = "", ""
+		reversed.SimilarityIndex = ""
+		reversed.OldMode, reversed.NewMode = f.NewMode, ""
+		if f.OldHash != "" {
+			reversed.NewHash = ObjectID(strings.Repeat("0", len(f.OldHash)))
+		}
+	}
+	if f.Header != "" {
+		reversed.Header = reversed.headerLine()
+	}
+	for _, hunk := range f.Hunks {
+		reversed.Hunks = append(reversed.Hunks, hunk.Reverse())
+	}
+	return reversed
+}
+
+// Reverse returns a Hunk with the ranges swapped and the added and deleted
+// lines flipped. Deleted lines are moved before added ones in each change,
+// which is the order diffs are written in.
+func (h *Hunk) Reverse() *Hunk {
+	reversed := &Hunk{
+		OldStart:     h.NewStart,
+		NewStart:     h.OldStart,
+		OldLineCount: h.NewLineCount,
+		NewLineCount: h.OldLineCount,
+		Section:      h.Section,
+		Lines:        make([]*HunkLine, 0, len(h.Lines)),
+	}
+	var added []*HunkLine
+	for _, line := range h.Lines {
+		switch line.Type {
+		case HunkLineAdded:
+			reversed.Lines = append(reversed.Lines, &HunkLine{Type: HunkLineDeleted, Content: line.Content})
+		case HunkLineDeleted:
+			added = append(added, &HunkLine{Type: HunkLineAdded, Content: line.Content})
+		default:
+			reversed.Lines = append(reversed.Lines, added...)
+			added = added[:0]
+			reversed.Lines = append(reversed.Lines, &HunkLine{Type: line.Type, Content: line.Content})
+		}
+	}
+	reversed.Lines = append(reversed.Lines, added...)
+	return reversed
+}
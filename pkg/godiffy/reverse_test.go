@@ -0,0 +1,68 @@
+package godiffy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The fixtures come from "git diff -M" and "git diff -M -R" on the same
+// change, with the a/ and b/ prefixes of the reversed diff swapped back and
+// its files in the order of the forward diff.
+func TestReverseMatchesGit(t *testing.T) {
+	forward, err := os.ReadFile(filepath.Join("testdata", "reverse", "forward.diff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "reverse", "reverse.diff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := Parse(string(forward))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if got := diff.Reverse().String(); got != string(want) {
+		t.Errorf("Reverse() =\n%s\nwant\n%s", got, want)
+	}
+	if got := diff.String(); got != string(forward) {
+		t.Errorf("Reverse modified the receiver:\n%s", got)
+	}
+}
+
+func TestReverseStatuses(t *testing.T) {
+	diff := &Diff{Files: []*FileDiff{
+		{Status: FileStatusNew, OldPath: "n.txt", NewPath: "n.txt", NewMode: "100644"},
+		{Status: FileStatusDeleted, OldPath: "d.txt", NewPath: "d.txt", OldMode: "100755"},
+		{Status: FileStatusRenamed, OldPath: "from.txt", NewPath: "to.txt", OldName: "from.txt", NewName: "to.txt"},
+		{Status: FileStatusCopied, OldPath: "src.txt", NewPath: "copy.txt", OldName: "src.txt", NewName: "copy.txt",
+			NewMode: "100644", OldHash: "f00c965", NewHash: "088bd5d", SimilarityIndex: "81%"},
+	}}
+	reversed := diff.Reverse()
+
+	if f := reversed.Files[0]; f.Status != FileStatusDeleted || f.OldMode != "100644" || f.NewMode != "" {
+		t.Errorf("expected new file to become a deletion of mode 100644, got status %d modes %q %q", f.Status, f.OldMode, f.NewMode)
+	}
+	if f := reversed.Files[1]; f.Status != FileStatusNew || f.NewMode != "100755" {
+		t.Errorf("expected deleted file to become new with mode 100755, got status %d mode %q", f.Status, f.NewMode)
+	}
+	if f := reversed.Files[2]; f.Status != FileStatusRenamed || f.OldPath != "to.txt" || f.NewPath != "from.txt" || f.OldName != "to.txt" || f.NewName != "from.txt" {
+		t.Errorf("expected rename from to.txt to from.txt, got %s -> %s", f.OldPath, f.NewPath)
+	}
+	if f := reversed.Files[3]; f.Status != FileStatusDeleted || f.OldPath != "copy.txt" || f.NewPath != "copy.txt" ||
+		f.OldMode != "100644" || f.NewMode != "" || f.OldHash != "088bd5d" || !f.NewHash.IsNull() || f.SimilarityIndex != "" {
+		t.Errorf("expected copy to become a deletion of copy.txt, got %+v", f)
+	}
+}
+
+func TestReverseTwice(t *testing.T) {
+	file, err := Compute("a\nb\nc\nd\ne\n", "a\nB\nc\nD\nE\nf\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.OldPath, file.NewPath = "x.txt", "x.txt"
+	if got, want := file.Reverse().Reverse().String(), file.String(); got != want {
+		t.Errorf("double reverse =\n%s\nwant\n%s", got, want)
+	}
+}
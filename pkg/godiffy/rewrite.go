@@ -0,0 +1,90 @@
+package godiffy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RewritePaths returns a copy of d with every old and new path passed
+// through rewrite. Hunks are shared with d. The raw paths no longer match
+// and are cleared.
+func (d *Diff) RewritePaths(rewrite func(path string) (string, error)) (*Diff, error) {
+	result := &Diff{Files: make([]*FileDiff, 0, len(d.Files))}
+	for _, file := range d.Files {
+		rewritten := *file
+		for _, p := range []*string{&rewritten.OldPath, &rewritten.NewPath, &rewritten.OldName, &rewritten.NewName} {
+			if *p == "" {
+				continue
+			}
+			newPath, err := rewrite(*p)
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewrite path %s: %w", *p, err)
+			}
+			*p = newPath
+		}
+		rewritten.OldRawPath, rewritten.NewRawPath = "", ""
+		if rewritten.Header != "" {
+			rewritten.Header = rewritten.headerLine()
+		}
+		result.Files = append(result.Files, &rewritten)
+	}
+	return result, nil
+}
+
+// StripComponents removes the first n directories from every path, like
+// "patch -pN" does with the a/ and b/ prefixes already gone.
+func (d *Diff) StripComponents(n int) (*Diff, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("invalid component count: %d", n)
+	}
+	return d.RewritePaths(func(p string) (string, error) {
+		rest := p
+		for range n {
+			_, after, ok := strings.Cut(rest, "/")
+			if !ok || after == "" {
+				return "", fmt.Errorf("path has fewer than %d leading directories", n)
+			}
+			rest = after
+		}
+		return rest, nil
+	})
+}
+
+// AddPrefix moves every path below the directory prefix.
+func (d *Diff) AddPrefix(prefix string) (*Diff, error) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return d.RewritePaths(keepPath)
+	}
+	return d.RewritePaths(func(p string) (string, error) {
+		return prefix + "/" + p, nil
+	})
+}
+
+// MapPaths moves the paths inside the directory from to the directory to.
+// Other paths are left alone. An empty from or to stands for the top
+// directory.
+func (d *Diff) MapPaths(from, to string) (*Diff, error) {
+	from, to = strings.TrimSuffix(from, "/"), strings.TrimSuffix(to, "/")
+	return d.RewritePaths(func(p string) (string, error) {
+		var rest string
+		switch {
+		case from == "":
+			rest = p
+		case strings.HasPrefix(p, from+"/"):
+			rest = p[len(from)+1:]
+		case p == from:
+			return "", fmt.Errorf("cannot map directory %s onto a file", from)
+		default:
+			return p, nil
+		}
+		if to == "" {
+			return rest, nil
+		}
+		return to + "/" + rest, nil
+	})
+}
+
+func keepPath(p string) (string, error) {
+	return p, nil
+}
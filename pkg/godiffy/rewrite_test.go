@@ -0,0 +1,67 @@
+package godiffy
+
+import "testing"
+
+func rewriteTestDiff() *Diff {
+	return &Diff{Files: []*FileDiff{
+		{Header: "diff --git a/upstream/lib/a.go b/upstream/lib/a.go\n", Status: FileStatusModified, OldPath: "upstream/lib/a.go", NewPath: "upstream/lib/a.go"},
+		{Status: FileStatusRenamed, OldPath: "upstream/lib/b.go", NewPath: "upstream/cmd/b.go", OldName: "upstream/lib/b.go", NewName: "upstream/cmd/b.go"},
+		{Status: FileStatusNew, OldPath: "README.md", NewPath: "README.md"},
+	}}
+}
+
+func TestStripComponents(t *testing.T) {
+	diff := rewriteTestDiff()
+	diff.Files = diff.Files[:2]
+	stripped, err := diff.StripComponents(1)
+	if err != nil {
+		t.Fatalf("StripComponents returned error: %v", err)
+	}
+	if got := stripped.Files[0].NewPath; got != "lib/a.go" {
+		t.Errorf("expected lib/a.go, got %s", got)
+	}
+	if got, want := stripped.Files[0].Header, "diff --git a/lib/a.go b/lib/a.go\n"; got != want {
+		t.Errorf("Header = %q, want %q", got, want)
+	}
+	if f := stripped.Files[1]; f.OldName != "lib/b.go" || f.NewName != "cmd/b.go" {
+		t.Errorf("expected rename from lib/b.go to cmd/b.go, got %s -> %s", f.OldName, f.NewName)
+	}
+	if diff.Files[0].NewPath != "upstream/lib/a.go" {
+		t.Errorf("StripComponents modified the receiver")
+	}
+
+	if _, err := rewriteTestDiff().StripComponents(1); err == nil {
+		t.Error("expected error stripping a top level path, got nil")
+	}
+}
+
+func TestAddPrefix(t *testing.T) {
+	prefixed, err := rewriteTestDiff().AddPrefix("third_party/")
+	if err != nil {
+		t.Fatalf("AddPrefix returned error: %v", err)
+	}
+	if got := prefixed.Files[2].NewPath; got != "third_party/README.md" {
+		t.Errorf("expected third_party/README.md, got %s", got)
+	}
+}
+
+func TestMapPaths(t *testing.T) {
+	mapped, err := rewriteTestDiff().MapPaths("upstream/lib", "pkg/vendored")
+	if err != nil {
+		t.Fatalf("MapPaths returned error: %v", err)
+	}
+	want := []struct{ oldPath, newPath string }{
+		{"pkg/vendored/a.go", "pkg/vendored/a.go"},
+		{"pkg/vendored/b.go", "upstream/cmd/b.go"},
+		{"README.md", "README.md"},
+	}
+	for i, w := range want {
+		if f := mapped.Files[i]; f.OldPath != w.oldPath || f.NewPath != w.newPath {
+			t.Errorf("file %d: got %s -> %s, want %s -> %s", i, f.OldPath, f.NewPath, w.oldPath, w.newPath)
+		}
+	}
+
+	if _, err := rewriteTestDiff().MapPaths("README.md", "docs"); err == nil {
+		t.Error("expected error mapping a file as a directory, got nil")
+	}
+}
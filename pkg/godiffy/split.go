@@ -0,0 +1,137 @@
+package godiffy
+
+// Split cuts the hunk into one hunk per run of changed lines, like the "s"
+// command of "git add -p". The context between two runs is given to both
+// of them, and the new line numbers assume the earlier pieces are applied
+// too. A hunk with a single run comes back as a one element copy.
+func (h *Hunk) Split() []*Hunk {
+	var pieces []*Hunk
+	oldLine := firstLineIndex(h.OldStart, h.OldLineCount)
+	newLine := firstLineIndex(h.NewStart, h.NewLineCount)
+	start := 0 // first line of the current piece
+	for i := 0; i < len(h.Lines); {
+		// Skip the leading context, then the run of changes.
+		j := i
+		for j < len(h.Lines) && h.Lines[j].Type == HunkLineContext {
+			j++
+		}
+		if j == len(h.Lines) {
+			break
+		}
+		for j < len(h.Lines) && h.Lines[j].Type != HunkLineContext {
+			j++
+		}
+		end := j
+		for end < len(h.Lines) && h.Lines[end].Type == HunkLineContext {
+			end++
+		}
+		if end == len(h.Lines) {
+			j = end
+		}
+
+		piece := &Hunk{Lines: make([]*HunkLine, 0, end-start)}
+		for _, line := range h.Lines[start:end] {
+			piece.Lines = append(piece.Lines, &HunkLine{Type: line.Type, Content: line.Content})
+		}
+		if len(pieces) == 0 {
+			piece.Section = h.Section
+		}
+		piece.recount()
+		piece.OldStart = hunkStart(oldLine, piece.OldLineCount)
+		piece.NewStart = hunkStart(newLine, piece.NewLineCount)
+		pieces = append(pieces, piece)
+
+		// The next piece starts with the context that ends this one.
+		for _, line := range h.Lines[start:j] {
+			if line.Type != HunkLineAdded {
+				oldLine++
+			}
+			if line.Type != HunkLineDeleted {
+				newLine++
+			}
+		}
+		start, i = j, j
+	}
+	if len(pieces) == 0 {
+		piece := *h
+		piece.Lines = append([]*HunkLine(nil), h.Lines...)
+		return []*Hunk{&piece}
+	}
+	return pieces
+}
+
+// SelectLines returns a copy of the hunk that keeps only the selected added
+// and deleted lines. Deleted lines that are not selected become context and
+// added ones are dropped. The line counts are recomputed, and the new start
+// is placed as if no earlier hunk had changed the line count.
+func (h *Hunk) SelectLines(selected func(line int) bool) *Hunk {
+	result := &Hunk{
+		OldStart: h.OldStart,
+		Section:  h.Section,
+		Lines:    make([]*HunkLine, 0, len(h.Lines)),
+	}
+	for i, line := range h.Lines {
+		switch {
+		case line.Type == HunkLineContext || selected(i):
+			result.Lines = append(result.Lines, &HunkLine{Type: line.Type, Content: line.Content})
+		case line.Type == HunkLineDeleted:
+			result.Lines = append(result.Lines, &HunkLine{Type: HunkLineContext, Content: line.Content})
+		}
+	}
+	result.recount()
+	result.NewStart = hunkStart(firstLineIndex(h.OldStart, h.OldLineCount), result.NewLineCount)
+	return result
+}
+
+// SelectLines returns a copy of the file diff that keeps only the selected
+// lines of each hunk, as Hunk.SelectLines does. Hunks left without changes
+// are dropped and the new starts of the others are shifted by the lines the
+// kept hunks before them add or remove.
+func (f *FileDiff) SelectLines(selected func(hunk, line int) bool) *FileDiff {
+	result := *f
+	result.Hunks = nil
+	delta := 0
+	for i, hunk := range f.Hunks {
+		picked := hunk.SelectLines(func(line int) bool {
+			return selected(i, line)
+		})
+		if !picked.hasChanges() {
+			continue
+		}
+		picked.NewStart = hunkStart(firstLineIndex(picked.OldStart, picked.OldLineCount)+delta, picked.NewLineCount)
+		delta += picked.NewLineCount - picked.OldLineCount
+		result.Hunks = append(result.Hunks, picked)
+	}
+	return &result
+}
+
+func (h *Hunk) hasChanges() bool {
+	for _, line := range h.Lines {
+		if line.Type != HunkLineContext {
+			return true
+		}
+	}
+	return false
+}
+
+// recount sets the line counts from the hunk's lines.
+func (h *Hunk) recount() {
+	h.OldLineCount, h.NewLineCount = 0, 0
+	for _, line := range h.Lines {
+		if line.Type != HunkLineAdded {
+			h.OldLineCount++
+		}
+		if line.Type != HunkLineDeleted {
+			h.NewLineCount++
+		}
+	}
+}
+
+// firstLineIndex converts a start from a hunk header into the 0-based index
+// of the range's first line, undoing hunkStart.
+func firstLineIndex(start, count int) int {
+	if count == 0 {
+		return start
+	}
+	return max(start-1, 0)
+}
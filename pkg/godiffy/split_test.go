@@ -0,0 +1,106 @@
+package godiffy
+
+import "testing"
+
+// The expected pieces are what "git add -p" shows after splitting.
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []string
+	}{
+		{
+			name: "two runs",
+			old:  "func main\n1\n2\n3\n4\n5\n6\n7\n8\n",
+			new:  "func main\n1\nTWO\n3\n4\nfive\nsix\n6\n7\n8\n",
+			want: []string{
+				"@@ -1,5 +1,5 @@\n func main\n 1\n-2\n+TWO\n 3\n 4\n",
+				"@@ -4,6 +4,7 @@\n 3\n 4\n-5\n+five\n+six\n 6\n 7\n 8\n",
+			},
+		},
+		{
+			name: "section on first piece",
+			old:  "func main\n0\n0\n0\n0\n1\n2\n3\n4\n5\n6\n7\n8\n",
+			new:  "func main\n0\n0\n0\n0\n1\nTWO\n3\n4\nfive\n6\n7\n8\n",
+			want: []string{
+				"@@ -4,6 +4,6 @@ func main\n 0\n 0\n 1\n-2\n+TWO\n 3\n 4\n",
+				"@@ -8,6 +8,6 @@\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+			},
+		},
+		{
+			name: "single run",
+			old:  "a\nb\nc\n",
+			new:  "a\nB\nc\n",
+			want: []string{"@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Compute(tt.old, tt.new, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pieces := file.Hunks[0].Split()
+			if len(pieces) != len(tt.want) {
+				t.Fatalf("expected %d pieces, got %d", len(tt.want), len(pieces))
+			}
+			for i, piece := range pieces {
+				if got := piece.String(); got != tt.want[i] {
+					t.Errorf("piece %d =\n%s\nwant\n%s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHunkSelectLines(t *testing.T) {
+	file, err := Compute("a\nb\nc\nd\n", "a\nB\nc\nD\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lines: " a", "-b", "+B", " c", "-d", "+D"; keep only the second change.
+	hunk := file.Hunks[0].SelectLines(func(line int) bool {
+		return line >= 4
+	})
+	if got, want := hunk.String(), "@@ -1,4 +1,4 @@\n a\n b\n c\n-d\n+D\n"; got != want {
+		t.Errorf("SelectLines =\n%s\nwant\n%s", got, want)
+	}
+
+	// Keeping a deletion without its replacement shortens the new side.
+	hunk = file.Hunks[0].SelectLines(func(line int) bool {
+		return line == 1
+	})
+	if got, want := hunk.String(), "@@ -1,4 +1,3 @@\n a\n-b\n c\n d\n"; got != want {
+		t.Errorf("SelectLines =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFileDiffSelectLines(t *testing.T) {
+	oldText := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	newText := "1\nnew\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\neleven\n12\n"
+	file, err := Compute(oldText, newText, &DiffOptions{Context: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(file.Hunks))
+	}
+
+	// Dropping the first hunk's addition moves the second one up.
+	selected := file.SelectLines(func(hunk, line int) bool {
+		return hunk == 1
+	})
+	if len(selected.Hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(selected.Hunks))
+	}
+	if got, want := selected.Hunks[0].String(), "@@ -11,2 +11,3 @@\n 11\n+eleven\n 12\n"; got != want {
+		t.Errorf("hunk =\n%s\nwant\n%s", got, want)
+	}
+
+	selected = file.SelectLines(func(hunk, line int) bool {
+		return true
+	})
+	if got, want := selected.String(), file.String(); got != want {
+		t.Errorf("selecting everything =\n%s\nwant\n%s", got, want)
+	}
+}
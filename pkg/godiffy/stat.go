@@ -0,0 +1,347 @@
+package godiffy
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DefaultStatWidth       = 80
+	DefaultDirstatPermille = 30
+)
+
+// Stat counts the added and deleted lines of the file. Binary files have no
+// line counts.
+func (f *FileDiff) Stat() *FileStat {
+	oldPath, newPath := f.displayPaths()
+	stat := &FileStat{OldPath: oldPath, NewPath: newPath, IsBinary: f.IsBinary && len(f.Hunks) == 0}
+	for _, hunk := range f.Hunks {
+		for _, line := range hunk.Lines {
+			switch line.Type {
+			case HunkLineAdded:
+				stat.Added++
+			case HunkLineDeleted:
+				stat.Deleted++
+			}
+		}
+	}
+	return stat
+}
+
+// Stat counts the added and deleted lines of every file and of the whole
+// diff.
+func (d *Diff) Stat() *DiffStat {
+	stat := &DiffStat{}
+	for _, file := range d.Files {
+		fileStat := file.Stat()
+		stat.Files = append(stat.Files, fileStat)
+		if fileStat.IsBinary {
+			continue
+		}
+		stat.Added += fileStat.Added
+		stat.Deleted += fileStat.Deleted
+	}
+	return stat
+}
+
+// Name returns the path shown for the file, which for renames and copies is
+// "old => new" with the common leading and trailing directories pulled out,
+// as in "src/{a => b}/main.go". Paths are quoted like git does.
+func (s *FileStat) Name() string {
+	if s.OldPath == s.NewPath || s.OldPath == "" {
+		return quotePath(s.NewPath)
+	}
+	a, b := s.OldPath, s.NewPath
+	if needsQuoting(a) || needsQuoting(b) {
+		return quotePath(a) + " => " + quotePath(b)
+	}
+
+	prefix := 0
+	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
+		if a[i] == '/' {
+			prefix = i + 1
+		}
+	}
+	// The suffix starts at a slash and may reuse the slash that ends the
+	// prefix.
+	suffix, limit := 0, prefix
+	if prefix > 0 {
+		limit--
+	}
+	for i, j := len(a)-1, len(b)-1; i >= limit && j >= limit && a[i] == b[j]; i, j = i-1, j-1 {
+		if a[i] == '/' {
+			suffix = len(a) - i
+		}
+	}
+	aMid := max(len(a)-prefix-suffix, 0)
+	bMid := max(len(b)-prefix-suffix, 0)
+
+	var name strings.Builder
+	if prefix+suffix > 0 {
+		name.WriteString(a[:prefix] + "{")
+	}
+	name.WriteString(a[prefix:prefix+aMid] + " => " + b[prefix:prefix+bMid])
+	if prefix+suffix > 0 {
+		name.WriteString("}" + a[len(a)-suffix:])
+	}
+	return name.String()
+}
+
+// String renders the stat like "git diff --stat" with the default options.
+func (s *DiffStat) String() string {
+	return s.Format(nil)
+}
+
+// Format renders the stat like "git diff --stat": one line per file with
+// the number of changed lines and a bar of '+' and '-' scaled to fit the
+// width, followed by the summary line. A nil opts uses an 80 column width.
+func (s *DiffStat) Format(opts *StatOptions) string {
+	if opts == nil {
+		opts = &StatOptions{}
+	}
+	width := opts.Width
+	if width <= 0 {
+		width = DefaultStatWidth
+	}
+
+	maxChange, maxLen, numberWidth, binWidth := 0, 0, 0, 0
+	names := make([]string, len(s.Files))
+	for i, file := range s.Files {
+		names[i] = file.Name()
+		maxLen = max(maxLen, utf8.RuneCountInString(names[i]))
+		if file.IsBinary {
+			binWidth = max(binWidth, 14+decimalWidth(file.Added)+decimalWidth(file.Deleted))
+			numberWidth = 3
+			continue
+		}
+		maxChange = max(maxChange, file.Added+file.Deleted)
+	}
+	numberWidth = max(numberWidth, decimalWidth(maxChange))
+
+	// This follows git's show_stats: the name gets what it needs, up to
+	// 5/8 of the width when space runs out, and the graph the rest.
+	width = max(width, 16+6+numberWidth)
+	graphWidth := maxChange
+	if maxChange+4 <= binWidth {
+		graphWidth = binWidth - 4
+	}
+	if opts.GraphWidth > 0 && opts.GraphWidth < graphWidth {
+		graphWidth = opts.GraphWidth
+	}
+	nameWidth := maxLen
+	if opts.NameWidth > 0 && opts.NameWidth < maxLen {
+		nameWidth = opts.NameWidth
+	}
+	if nameWidth+numberWidth+6+graphWidth > width {
+		if graphWidth > width*3/8-numberWidth-6 {
+			graphWidth = max(width*3/8-numberWidth-6, 6)
+		}
+		if opts.GraphWidth > 0 && graphWidth > opts.GraphWidth {
+			graphWidth = opts.GraphWidth
+		}
+		if nameWidth > width-numberWidth-6-graphWidth {
+			nameWidth = width - numberWidth - 6 - graphWidth
+		} else {
+			graphWidth = width - numberWidth - 6 - nameWidth
+		}
+	}
+
+	var b strings.Builder
+	for i, file := range s.Files {
+		name, prefix := names[i], ""
+		length := nameWidth
+		if nameLen := utf8.RuneCountInString(name); nameWidth < nameLen {
+			prefix = "..."
+			length = max(length-3, 0)
+			for ; nameLen > length; nameLen-- {
+				_, size := utf8.DecodeRuneInString(name)
+				name = name[size:]
+			}
+			if slash := strings.IndexByte(name, '/'); slash != -1 {
+				name = name[slash:]
+			}
+		}
+		padding := max(length-utf8.RuneCountInString(name), 0)
+
+		if file.IsBinary {
+			fmt.Fprintf(&b, " %s%s%*s | %*s", prefix, name, padding, "", numberWidth, "Bin")
+			if file.Added == 0 && file.Deleted == 0 {
+				b.WriteString("\n")
+				continue
+			}
+			fmt.Fprintf(&b, " %d -> %d bytes\n", file.Deleted, file.Added)
+			continue
+		}
+		add, del := file.Added, file.Deleted
+		if graphWidth <= maxChange {
+			total := scaleLinear(add+del, graphWidth, maxChange)
+			if total < 2 && add > 0 && del > 0 {
+				total = 2
+			}
+			if add < del {
+				add = scaleLinear(add, graphWidth, maxChange)
+				del = total - add
+			} else {
+				del = scaleLinear(del, graphWidth, maxChange)
+				add = total - del
+			}
+		}
+		separator := ""
+		if file.Added+file.Deleted > 0 {
+			separator = " "
+		}
+		fmt.Fprintf(&b, " %s%s%*s | %*d%s%s%s\n", prefix, name, padding, "", numberWidth, file.Added+file.Deleted, separator,
+			strings.Repeat("+", add), strings.Repeat("-", del))
+	}
+	b.WriteString(s.Shortstat())
+	return b.String()
+}
+
+// scaleLinear scales a change count to the graph width, giving every
+// change at least one column.
+func scaleLinear(n, width, maxChange int) int {
+	if n == 0 {
+		return 0
+	}
+	return 1 + n*(width-1)/maxChange
+}
+
+func decimalWidth(n int) int {
+	return len(strconv.Itoa(n))
+}
+
+// Shortstat renders only the summary line, like "git diff --shortstat".
+func (s *DiffStat) Shortstat() string {
+	files, added, deleted := len(s.Files), s.Added, s.Deleted
+	if files == 0 {
+		return " 0 files changed\n"
+	}
+	var b strings.Builder
+	if files == 1 {
+		b.WriteString(" 1 file changed")
+	} else {
+		fmt.Fprintf(&b, " %d files changed", files)
+	}
+	// Like git, an empty side is only left out when the other one is not
+	// empty.
+	if added > 0 || deleted == 0 {
+		fmt.Fprintf(&b, ", %d insertion%s(+)", added, plural(added))
+	}
+	if deleted > 0 || added == 0 {
+		fmt.Fprintf(&b, ", %d deletion%s(-)", deleted, plural(deleted))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// Numstat renders the counts like "git diff --numstat", with "-" for the
+// counts of binary files. With nulTerminated set the output matches
+// "--numstat -z", where renames list both paths as separate fields.
+func (s *DiffStat) Numstat(nulTerminated bool) string {
+	var b strings.Builder
+	for _, file := range s.Files {
+		if file.IsBinary {
+			b.WriteString("-\t-\t")
+		} else {
+			fmt.Fprintf(&b, "%d\t%d\t", file.Added, file.Deleted)
+		}
+		switch {
+		case !nulTerminated:
+			b.WriteString(file.Name() + "\n")
+		case file.OldPath != file.NewPath && file.OldPath != "":
+			b.WriteString("\x00" + file.OldPath + "\x00" + file.NewPath + "\x00")
+		default:
+			b.WriteString(file.NewPath + "\x00")
+		}
+	}
+	return b.String()
+}
+
+// Dirstat distributes the changed lines over the directories they are in,
+// like "git diff --dirstat=lines". Directories with less than the cutoff
+// are left out, as are directories whose changes all come from a single
+// subdirectory. A nil opts uses DefaultDirstatOptions.
+func (s *DiffStat) Dirstat(opts *DirstatOptions) []*DirStat {
+	if opts == nil {
+		opts = DefaultDirstatOptions()
+	}
+	type dirstatFile struct {
+		name    string
+		changed int
+	}
+	var files []dirstatFile
+	total := 0
+	for _, file := range s.Files {
+		changed := file.Added + file.Deleted
+		switch {
+		case opts.ByFile && changed == 0 && !file.IsBinary:
+			// Only the mode or the path changed.
+			continue
+		case opts.ByFile:
+			changed = 1
+		case file.IsBinary:
+			// Binary counts are in bytes; git counts 64 of them as a line.
+			changed = (changed + 63) / 64
+		}
+		files = append(files, dirstatFile{name: file.NewPath, changed: changed})
+		total += changed
+	}
+	slices.SortFunc(files, func(a, b dirstatFile) int {
+		return strings.Compare(a.name, b.name)
+	})
+	if total == 0 {
+		return nil
+	}
+
+	var result []*DirStat
+	var gather func(base string) int
+	gather = func(base string) int {
+		sum, sources := 0, 0
+		for len(files) > 0 {
+			name := files[0].name
+			if !strings.HasPrefix(name, base) {
+				break
+			}
+			if slash := strings.IndexByte(name[len(base):], '/'); slash != -1 {
+				sum += gather(name[:len(base)+slash+1])
+				sources++
+			} else {
+				sum += files[0].changed
+				files = files[1:]
+				sources += 2
+			}
+		}
+		// Like git, the top level and directories whose changes all
+		// come from one subdirectory are not reported.
+		if base != "" && sources != 1 && sum > 0 {
+			if permille := sum * 1000 / total; permille >= opts.Permille {
+				result = append(result, &DirStat{Dir: base, Permille: permille})
+				if !opts.Cumulative {
+					return 0
+				}
+			}
+		}
+		return sum
+	}
+	gather("")
+	return result
+}
+
+func DefaultDirstatOptions() *DirstatOptions {
+	return &DirstatOptions{Permille: DefaultDirstatPermille}
+}
+
+// String renders the share like git does, as in " 42.5% src/".
+func (d *DirStat) String() string {
+	return fmt.Sprintf("%4d.%01d%% %s\n", d.Permille/10, d.Permille%10, d.Dir)
+}
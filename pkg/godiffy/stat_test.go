@@ -0,0 +1,142 @@
+package godiffy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The fixtures come from "git diff -M" with the various stat options on the
+// change in input.diff. Git knows the sizes of binary files, which a patch
+// does not carry, so gitStat fills them in.
+func gitStat(t *testing.T) *DiffStat {
+	t.Helper()
+	input, err := os.ReadFile(filepath.Join("testdata", "stat", "input.diff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := Parse(string(input))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	stat := diff.Stat()
+	for _, file := range stat.Files {
+		if file.NewPath == "img.bin" {
+			file.Deleted, file.Added = 5, 6
+		}
+	}
+	return stat
+}
+
+func readStatFixture(t *testing.T, name string) string {
+	t.Helper()
+	want, err := os.ReadFile(filepath.Join("testdata", "stat", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(want)
+}
+
+func TestStatCounts(t *testing.T) {
+	stat := gitStat(t)
+	if stat.Added != 117 || stat.Deleted != 115 {
+		t.Errorf("totals: got +%d -%d, want +117 -115", stat.Added, stat.Deleted)
+	}
+	if len(stat.Files) != 9 {
+		t.Fatalf("got %d files, want 9", len(stat.Files))
+	}
+	big := stat.Files[5]
+	if big.NewPath != "src/big.txt" || big.Added != 111 || big.Deleted != 111 || big.IsBinary {
+		t.Errorf("got %+v, want src/big.txt with +111 -111", big)
+	}
+	if !stat.Files[2].IsBinary {
+		t.Errorf("img.bin is not marked binary")
+	}
+}
+
+func TestStatFormatMatchesGit(t *testing.T) {
+	stat := gitStat(t)
+	tests := []struct {
+		fixture string
+		opts    *StatOptions
+	}{
+		{"stat.txt", nil},
+		{"stat-50.txt", &StatOptions{Width: 50}},
+		{"stat-120-20.txt", &StatOptions{Width: 120, NameWidth: 20}},
+		{"stat-graph-10.txt", &StatOptions{GraphWidth: 10}},
+	}
+	for _, tt := range tests {
+		want := readStatFixture(t, tt.fixture)
+		if got := stat.Format(tt.opts); got != want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.fixture, got, want)
+		}
+	}
+	if got, want := stat.Shortstat(), " 9 files changed, 117 insertions(+), 115 deletions(-)\n"; got != want {
+		t.Errorf("Shortstat() = %q, want %q", got, want)
+	}
+}
+
+func TestNumstatMatchesGit(t *testing.T) {
+	stat := gitStat(t)
+	if got, want := stat.Numstat(false), readStatFixture(t, "numstat.txt"); got != want {
+		t.Errorf("Numstat(false) =\n%s\nwant\n%s", got, want)
+	}
+	if got, want := stat.Numstat(true), readStatFixture(t, "numstat-z.txt"); got != want {
+		t.Errorf("Numstat(true) = %q, want %q", got, want)
+	}
+}
+
+func TestDirstatMatchesGit(t *testing.T) {
+	stat := gitStat(t)
+	tests := []struct {
+		fixture string
+		opts    *DirstatOptions
+	}{
+		{"dirstat-lines.txt", nil},
+		{"dirstat-lines-0.txt", &DirstatOptions{}},
+		{"dirstat-files-0-cumulative.txt", &DirstatOptions{ByFile: true, Cumulative: true}},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		for _, dir := range stat.Dirstat(tt.opts) {
+			b.WriteString(dir.String())
+		}
+		if got, want := b.String(), readStatFixture(t, tt.fixture); got != want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.fixture, got, want)
+		}
+	}
+}
+
+func TestStatName(t *testing.T) {
+	tests := []struct {
+		oldPath, newPath, want string
+	}{
+		{"a.txt", "a.txt", "a.txt"},
+		{"a.txt", "b.txt", "a.txt => b.txt"},
+		{"src/a.go", "src/b.go", "src/{a.go => b.go}"},
+		{"src/lib/x.go", "src/other/x.go", "src/{lib => other}/x.go"},
+		{"a/x.go", "b/x.go", "{a => b}/x.go"},
+		{"dir/x.go", "dir/sub/x.go", "dir/{ => sub}/x.go"},
+		{"dir/sub/x.go", "dir/x.go", "dir/{sub => }/x.go"},
+	}
+	for _, tt := range tests {
+		stat := &FileStat{OldPath: tt.oldPath, NewPath: tt.newPath}
+		if got := stat.Name(); got != tt.want {
+			t.Errorf("Name() for %s -> %s = %q, want %q", tt.oldPath, tt.newPath, got, tt.want)
+		}
+	}
+}
+
+func TestStatBinaryWithoutSizes(t *testing.T) {
+	diff := &Diff{Files: []*FileDiff{
+		{Status: FileStatusModified, OldPath: "img.bin", NewPath: "img.bin", IsBinary: true},
+	}}
+	want := " img.bin | Bin\n 1 file changed, 0 insertions(+), 0 deletions(-)\n"
+	if got := diff.Stat().String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := (&DiffStat{}).Shortstat(); got != " 0 files changed\n" {
+		t.Errorf("empty Shortstat() = %q", got)
+	}
+}
@@ -6,19 +6,49 @@ type Diff struct {
 
 type FileStatus int
 
+// FileMode is a mode as git writes it, six octal digits like "100644". The
+// empty mode stands for a side that does not exist or a mode not given.
+type FileMode string
+
+// ObjectID is a full or abbreviated object ID in hex, like "def456".
+type ObjectID string
+
 type FileDiff struct {
-	Header          string
-	OldHash         string
-	NewHash         string
-	SimilarityIndex string
-	OldPath         string
-	NewPath         string
-	OldName         string
-	NewName         string
-	OldMode         string
-	NewMode         string
-	Status          FileStatus
-	Hunks           []*Hunk
+	Header             string
+	OldHash            ObjectID
+	NewHash            ObjectID
+	SimilarityIndex    string
+	DissimilarityIndex string // how much of a rewritten file changed, like "97%", from "git diff -B"
+	OldPath            string
+	NewPath            string
+	OldName            string
+	NewName            string
+	OldRawPath         string // OldPath as written in the diff, prefix included, like "i/foo.txt"
+	NewRawPath         string
+	OldMode            FileMode
+	NewMode            FileMode
+	Status             FileStatus
+	IsBinary           bool
+	Submodule          *SubmoduleChange // set for gitlinks, entries with mode 160000
+	Hunks              []*Hunk
+}
+
+// SubmoduleChange holds the commits a gitlink moves between, from its
+// "Subproject commit" lines or from a "git diff --submodule=log" summary.
+type SubmoduleChange struct {
+	OldCommit ObjectID
+	NewCommit ObjectID
+	Dirty     bool   // the work tree has modified content, "-dirty" in patches
+	Untracked bool   // the work tree has untracked content
+	Summary   bool   // written as a --submodule=log summary instead of a patch
+	Note      string // what the summary says in parentheses, like "new submodule" or "rewind"
+	Log       []*SubmoduleCommit
+}
+
+// SubmoduleCommit is one line of a submodule log summary.
+type SubmoduleCommit struct {
+	Subject string
+	Removed bool // only reachable from the old commit, shown as "<"
 }
 
 type Hunk struct {
@@ -26,12 +56,126 @@ type Hunk struct {
 	NewStart     int
 	OldLineCount int
 	NewLineCount int
+	Section      string // text after the closing "@@", e.g. the enclosing function
 	Lines        []*HunkLine
 }
 
 type HunkLineKind int
 
+// LineEnding is the terminator of a line.
+type LineEnding int
+
+// HunkLine is one line of a hunk. Each line's terminator is recorded in
+// Content, which keeps it as the diff has it, "\r\n" included; Ending()
+// tells which one it is and Text() returns the line without it.
 type HunkLine struct {
 	Type    HunkLineKind
-	Content string
+	Content string // the line without its prefix, terminator included
+}
+
+// LinePair is a run of deleted lines and the run of added lines replacing
+// it, with the words or characters that changed between them.
+type LinePair struct {
+	Deleted  []*HunkLine
+	Added    []*HunkLine
+	Segments []*Segment
+}
+
+// Segment is a piece of a LinePair's text. Context segments appear on both
+// sides, deleted ones only in the old lines and added ones only in the new.
+type Segment struct {
+	Type HunkLineKind
+	Text string
+}
+
+type InterdiffStatus int
+
+// InterdiffResult lists what changed between two versions of a patch.
+type InterdiffResult struct {
+	Files []*FileInterdiff
+}
+
+type FileInterdiff struct {
+	Status InterdiffStatus
+	V1     *FileDiff // nil for files only in v2
+	V2     *FileDiff // nil for files only in v1
+	Hunks  []*HunkInterdiff
+}
+
+type HunkInterdiff struct {
+	Status InterdiffStatus
+	V1     *Hunk
+	V2     *Hunk
+	Lines  []*HunkLine // the diff between the lines of V1 and V2, prefixes included; all added or deleted for added and dropped hunks
+}
+
+// FileStat holds the number of lines a diff adds to and deletes from a
+// file. Binary files have no line counts; their Added and Deleted may be
+// set to the new and old size in bytes, which renders as git's
+// "Bin 5 -> 6 bytes".
+type FileStat struct {
+	OldPath  string
+	NewPath  string
+	Added    int
+	Deleted  int
+	IsBinary bool
+}
+
+type DiffStat struct {
+	Files   []*FileStat
+	Added   int // lines added to text files
+	Deleted int // lines deleted from text files
+}
+
+type StatOptions struct {
+	Width      int // total width of a line, like git's --stat=<width>
+	NameWidth  int // maximum width of the file names, like git's --stat-name-width
+	GraphWidth int // maximum width of the +/- bar, like git's --stat-graph-width
+}
+
+// DirStat is the share of a diff's changes that falls into a directory.
+type DirStat struct {
+	Dir      string // with a trailing slash
+	Permille int
+}
+
+type DirstatOptions struct {
+	Permille   int  // cutoff in tenths of a percent, like git's --dirstat=3
+	Cumulative bool // also count changes reported for subdirectories in their parents
+	ByFile     bool // count changed files instead of lines, like git's --dirstat=files
+}
+
+// ParseOptions declares the prefixes in front of the paths of a diff. With
+// all fields empty they are detected for every file.
+type ParseOptions struct {
+	SrcPrefix string // like git's --src-prefix
+	DstPrefix string // like git's --dst-prefix
+	NoPrefix  bool   // like git's --no-prefix
+}
+
+type IntralineMode int
+
+type IntralineOptions struct {
+	Mode      IntralineMode
+	WordRegex string // what makes up a word in IntralineModeWord, like git's --word-diff-regex
+}
+
+type Algorithm int
+
+// ObjectFormat is the hash algorithm of a repository's object IDs.
+type ObjectFormat int
+
+type DiffOptions struct {
+	Algorithm       Algorithm
+	Context         int  // unchanged lines shown around each change, like git's -U<n>
+	IndentHeuristic bool // shift ambiguous changes to indentation boundaries, like git's --indent-heuristic
+}
+
+type TreeDiffOptions struct {
+	DiffOptions
+	DetectRenames   bool
+	DetectCopies    bool
+	RenameThreshold int  // minimum similarity in percent for pairing files, like git's -M50%
+	FullIndex       bool // show full instead of abbreviated blob hashes on index lines
+	ObjectFormat    ObjectFormat
 }
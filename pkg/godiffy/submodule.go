@@ -0,0 +1,190 @@
+package godiffy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	subprojectPrefix = "Subproject commit "
+	dirtySuffix      = "-dirty"
+)
+
+// submoduleHeaderRegex matches the headers of "git diff --submodule=log",
+// like "Submodule sub 46841a5..9ffcedc:" or
+// "Submodule sub 0000000...ba4d687 (new submodule)".
+var submoduleHeaderRegex = regexp.MustCompile(`^Submodule (.+) ([0-9a-f]+)\.\.\.?([0-9a-f]+)(?: \((.+)\))?(:?)$`)
+
+// IsSubmodule reports whether the file is a gitlink, the commit of a
+// submodule recorded in the tree.
+func (f *FileDiff) IsSubmodule() bool {
+	return f.Submodule != nil || f.OldMode.IsGitlink() || f.NewMode.IsGitlink()
+}
+
+// parseSubproject fills in the submodule change of a gitlink patch from its
+// "Subproject commit" lines.
+func parseSubproject(file *FileDiff) error {
+	if file.Submodule != nil || (!file.OldMode.IsGitlink() && !file.NewMode.IsGitlink()) {
+		return nil
+	}
+	sub := &SubmoduleChange{}
+	for _, hunk := range file.Hunks {
+		for _, line := range hunk.Lines {
+			text, ok := strings.CutPrefix(strings.TrimSuffix(line.Content, "\n"), subprojectPrefix)
+			if !ok {
+				continue
+			}
+			text, dirty := strings.CutSuffix(text, dirtySuffix)
+			commit, err := ParseObjectID(text)
+			if err != nil {
+				return err
+			}
+			if line.Type != HunkLineAdded {
+				sub.OldCommit = commit
+			}
+			if line.Type != HunkLineDeleted {
+				sub.NewCommit, sub.Dirty = commit, dirty
+			}
+		}
+	}
+	file.Submodule = sub
+	return nil
+}
+
+// parseSubmoduleLine reads a "Submodule ..." line of --submodule=log output.
+// The lines about modified and untracked content come before the header of
+// the same submodule, so they share its entry.
+func parseSubmoduleLine(resultDiff *Diff, currentFile *FileDiff, line string) (*FileDiff, error) {
+	text := strings.TrimSuffix(line, "\n")
+	entry := func(path string) *FileDiff {
+		if currentFile != nil && currentFile.Submodule != nil && currentFile.Submodule.Summary &&
+			currentFile.NewPath == path && currentFile.Submodule.OldCommit == "" && currentFile.Submodule.NewCommit == "" {
+			return currentFile
+		}
+		file := &FileDiff{
+			Header:    line,
+			OldPath:   path,
+			NewPath:   path,
+			NewMode:   FileModeGitlink,
+			Status:    FileStatusModified,
+			Submodule: &SubmoduleChange{Summary: true},
+		}
+		resultDiff.Files = append(resultDiff.Files, file)
+		return file
+	}
+
+	if path, ok := strings.CutSuffix(text, " contains modified content"); ok {
+		file := entry(strings.TrimPrefix(path, "Submodule "))
+		file.Submodule.Dirty = true
+		return file, nil
+	}
+	if path, ok := strings.CutSuffix(text, " contains untracked content"); ok {
+		file := entry(strings.TrimPrefix(path, "Submodule "))
+		file.Submodule.Untracked = true
+		return file, nil
+	}
+	m := submoduleHeaderRegex.FindStringSubmatch(text)
+	if m == nil {
+		return nil, fmt.Errorf("invalid submodule line: %s", line)
+	}
+	file := entry(m[1])
+	file.OldHash, file.NewHash = ObjectID(m[2]), ObjectID(m[3])
+	file.Submodule.OldCommit, file.Submodule.NewCommit, file.Submodule.Note = file.OldHash, file.NewHash, m[4]
+	switch m[4] {
+	case "new submodule":
+		file.Status = FileStatusNew
+	case "submodule deleted":
+		file.Status, file.OldMode, file.NewMode = FileStatusDeleted, FileModeGitlink, ""
+	}
+	return file, nil
+}
+
+// parseSubmoduleLog reads a commit line of a submodule log summary, like
+// "  > second lib".
+func parseSubmoduleLog(currentFile *FileDiff, line string) error {
+	text := strings.TrimSuffix(line, "\n")
+	var removed bool
+	switch {
+	case strings.HasPrefix(text, "  > "):
+	case strings.HasPrefix(text, "  < "):
+		removed = true
+	default:
+		return fmt.Errorf("failed to parse line: %s", line)
+	}
+	currentFile.Submodule.Log = append(currentFile.Submodule.Log, &SubmoduleCommit{Subject: text[4:], Removed: removed})
+	return nil
+}
+
+// summaryString renders the change the way --submodule=log shows it.
+func (s *SubmoduleChange) summaryString(path string) string {
+	var b strings.Builder
+	if s.Untracked {
+		fmt.Fprintf(&b, "Submodule %s contains untracked content\n", path)
+	}
+	if s.Dirty {
+		fmt.Fprintf(&b, "Submodule %s contains modified content\n", path)
+	}
+	if s.OldCommit == "" && s.NewCommit == "" {
+		return b.String()
+	}
+
+	var removed, added bool
+	for _, commit := range s.Log {
+		removed = removed || commit.Removed
+		added = added || !commit.Removed
+	}
+	// Git uses two dots when one commit descends from the other.
+	dots := "..."
+	if s.Note == "rewind" || (s.Note == "" && !(removed && added)) {
+		dots = ".."
+	}
+	fmt.Fprintf(&b, "Submodule %s %s%s%s", path, s.OldCommit, dots, s.NewCommit)
+	if s.Note != "" {
+		fmt.Fprintf(&b, " (%s)", s.Note)
+	}
+	if s.Note == "" || s.Note == "rewind" {
+		b.WriteString(":")
+	}
+	b.WriteString("\n")
+	for _, commit := range s.Log {
+		marker := ">"
+		if commit.Removed {
+			marker = "<"
+		}
+		fmt.Fprintf(&b, "  %s %s\n", marker, commit.Subject)
+	}
+	return b.String()
+}
+
+// reverse returns the change that goes back from the new commit to the old.
+func (s *SubmoduleChange) reverse() *SubmoduleChange {
+	if s == nil {
+		return nil
+	}
+	reversed := &SubmoduleChange{
+		OldCommit: s.NewCommit,
+		NewCommit: s.OldCommit,
+		Summary:   s.Summary,
+		Note:      s.Note,
+	}
+	removed := false
+	for _, commit := range s.Log {
+		removed = removed || commit.Removed
+		reversed.Log = append(reversed.Log, &SubmoduleCommit{Subject: commit.Subject, Removed: !commit.Removed})
+	}
+	switch s.Note {
+	case "new submodule":
+		reversed.Note = "submodule deleted"
+	case "submodule deleted":
+		reversed.Note = "new submodule"
+	case "rewind":
+		reversed.Note = ""
+	case "":
+		// Undoing a fast-forward is a rewind.
+		if len(s.Log) > 0 && !removed {
+			reversed.Note = "rewind"
+		}
+	}
+	return reversed
+}
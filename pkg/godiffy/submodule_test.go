@@ -0,0 +1,148 @@
+package godiffy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readSubmoduleFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "submodule", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseSubmodulePatch(t *testing.T) {
+	input := readSubmoduleFixture(t, "patch.diff")
+	diff, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(diff.Files) != 2 {
+		t.Fatalf("got %d files, want 2", len(diff.Files))
+	}
+	if diff.Files[0].IsSubmodule() {
+		t.Errorf("readme is reported as a submodule")
+	}
+	sub := diff.Files[1]
+	if !sub.IsSubmodule() {
+		t.Fatalf("sub is not reported as a submodule")
+	}
+	want := SubmoduleChange{
+		OldCommit: "5796ffdde2d2e065f32b798ccac210b009f517b1",
+		NewCommit: "6131df29addf4b6586a4e7c0c6523ad32ae1cbba",
+		Dirty:     true,
+	}
+	if got := *sub.Submodule; got.OldCommit != want.OldCommit || got.NewCommit != want.NewCommit || got.Dirty != want.Dirty || got.Summary {
+		t.Errorf("Submodule = %+v, want %+v", got, want)
+	}
+	if got := diff.String(); got != input {
+		t.Errorf("round trip:\n%s\nwant\n%s", got, input)
+	}
+
+	deleted, err := Parse(readSubmoduleFixture(t, "deleted.diff"))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if got := deleted.Files[0].Submodule; got.OldCommit != "6131df29addf4b6586a4e7c0c6523ad32ae1cbba" || got.NewCommit != "" {
+		t.Errorf("deleted Submodule = %+v", got)
+	}
+}
+
+func TestParseSubmoduleLog(t *testing.T) {
+	input := readSubmoduleFixture(t, "log.diff")
+	diff, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(diff.Files) != 2 {
+		t.Fatalf("got %d files, want 2", len(diff.Files))
+	}
+	sub := diff.Files[1]
+	if !sub.IsSubmodule() || !sub.Submodule.Summary || !sub.Submodule.Dirty {
+		t.Fatalf("sub = %+v", sub.Submodule)
+	}
+	if sub.Submodule.OldCommit != "5796ffd" || sub.Submodule.NewCommit != "6131df2" {
+		t.Errorf("commits: got %s..%s", sub.Submodule.OldCommit, sub.Submodule.NewCommit)
+	}
+	if len(sub.Submodule.Log) != 2 || sub.Submodule.Log[0].Subject != "third lib" || sub.Submodule.Log[0].Removed {
+		t.Errorf("log = %+v", sub.Submodule.Log)
+	}
+	if got := diff.String(); got != input {
+		t.Errorf("round trip:\n%s\nwant\n%s", got, input)
+	}
+}
+
+func TestParseSubmoduleSummaries(t *testing.T) {
+	input := readSubmoduleFixture(t, "summaries.diff")
+	diff, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(diff.Files) != 7 {
+		t.Fatalf("got %d files, want 7", len(diff.Files))
+	}
+	if got := diff.String(); got != input {
+		t.Errorf("round trip:\n%s\nwant\n%s", got, input)
+	}
+	if got := diff.Files[3].Status; got != FileStatusNew {
+		t.Errorf("new submodule status = %v", got)
+	}
+	if got := diff.Files[4].Status; got != FileStatusDeleted {
+		t.Errorf("deleted submodule status = %v", got)
+	}
+	last := diff.Files[6].Submodule
+	if !last.Dirty || !last.Untracked || last.OldCommit != "" {
+		t.Errorf("dirty entry = %+v", last)
+	}
+}
+
+func TestReverseSubmodule(t *testing.T) {
+	diff, err := Parse(readSubmoduleFixture(t, "summaries.diff"))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	want := `Submodule sub 9ffcedc..46841a5 (rewind):
+  < third lib
+  < second lib
+Submodule sub 0d8d796...9ffcedc:
+  < side work
+  > third lib
+  > second lib
+Submodule sub 46841a5..9ffcedc:
+  > third lib
+Submodule added ba4d687...0000000 (submodule deleted)
+Submodule added 0000000...46841a5 (new submodule)
+Submodule gone 46841a5...46841a5 (commits not present)
+`
+	if got := diff.Reverse().String(); got != want {
+		t.Errorf("Reverse() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseRawSubmodule(t *testing.T) {
+	diff, err := ParseRaw(":160000 160000 46841a5 9ffcedc M\tsub\n:160000 000000 46841a5 0000000 D\tgone\n")
+	if err != nil {
+		t.Fatalf("ParseRaw returned error: %v", err)
+	}
+	if sub := diff.Files[0].Submodule; sub == nil || sub.OldCommit != "46841a5" || sub.NewCommit != "9ffcedc" {
+		t.Errorf("sub = %+v", sub)
+	}
+	if gone := diff.Files[1].Submodule; gone == nil || gone.OldCommit != "46841a5" || gone.NewCommit != "" {
+		t.Errorf("gone = %+v", gone)
+	}
+}
+
+func TestParseSubmoduleErrors(t *testing.T) {
+	for _, input := range []string{
+		"Submodule sub\n",
+		"Submodule sub 46841a5..9ffcedc:\n  * nope\n",
+	} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) returned no error", input)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package godiffy
+
+import "strings"
+
+// IsSymlink reports whether either side of the file is a symbolic link.
+// Git stores a link as a blob holding its target, without a trailing
+// newline, so its hunks change the target like a one-line file.
+func (f *FileDiff) IsSymlink() bool {
+	return f.OldMode.IsSymlink() || f.NewMode.IsSymlink()
+}
+
+// LinkTargets returns the targets the hunks give for the old and new side
+// of a symbolic link. A side that is not a link, or that does not exist,
+// gives an empty target.
+func (f *FileDiff) LinkTargets() (oldTarget, newTarget string) {
+	oldMode, newMode := f.OldMode, f.NewMode
+	if oldMode == "" && f.Status != FileStatusNew {
+		oldMode = newMode
+	}
+	if f.Status == FileStatusDeleted {
+		newMode = ""
+	}
+	var oldText, newText strings.Builder
+	for _, hunk := range f.Hunks {
+		for _, line := range hunk.Lines {
+			if line.Type != HunkLineAdded {
+				oldText.WriteString(line.Content)
+			}
+			if line.Type != HunkLineDeleted {
+				newText.WriteString(line.Content)
+			}
+		}
+	}
+	if oldMode.IsSymlink() {
+		oldTarget = oldText.String()
+	}
+	if newMode.IsSymlink() {
+		newTarget = newText.String()
+	}
+	return oldTarget, newTarget
+}
@@ -0,0 +1,83 @@
+package godiffy
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+// input.diff is what git prints for a retargeted, a new and a deleted link,
+// and for a file replaced by a link.
+func TestParseSymlinks(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "symlink", "input.diff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := Parse(string(data))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if got := diff.String(); got != string(data) {
+		t.Errorf("round trip:\n%s\nwant\n%s", got, data)
+	}
+
+	tests := []struct {
+		path              string
+		symlink           bool
+		oldTarget, target string
+	}{
+		{"gone", true, "other", ""},
+		{"link", true, "target.txt", "../outside"},
+		{"new", true, "", "target.txt"},
+		{"typ", false, "", ""},
+		{"typ", true, "", "target.txt"},
+	}
+	if len(diff.Files) != len(tests) {
+		t.Fatalf("got %d files, want %d", len(diff.Files), len(tests))
+	}
+	for i, tt := range tests {
+		file := diff.Files[i]
+		if file.NewPath != tt.path || file.IsSymlink() != tt.symlink {
+			t.Errorf("file %d: got %s, symlink %v, want %s, %v", i, file.NewPath, file.IsSymlink(), tt.path, tt.symlink)
+		}
+		if oldTarget, target := file.LinkTargets(); oldTarget != tt.oldTarget || target != tt.target {
+			t.Errorf("%s: LinkTargets() = %q, %q, want %q, %q", tt.path, oldTarget, target, tt.oldTarget, tt.target)
+		}
+	}
+
+	reversed := diff.Reverse()
+	if typ := reversed.Files[3]; typ.Status != FileStatusDeleted || !typ.IsSymlink() {
+		t.Errorf("reversed typ: got status %d, symlink %v, want the link deleted first", typ.Status, typ.IsSymlink())
+	}
+	if typ := reversed.Files[4]; typ.Status != FileStatusNew || typ.NewMode != "100644" {
+		t.Errorf("reversed typ: got status %d, mode %s, want the file created second", typ.Status, typ.NewMode)
+	}
+}
+
+func TestDiffFSSymlinks(t *testing.T) {
+	oldFS := fstest.MapFS{
+		"target.txt": {Data: []byte("hi\n")},
+		"link":       {Data: []byte("target.txt"), Mode: fs.ModeSymlink},
+		"gone":       {Data: []byte("other"), Mode: fs.ModeSymlink},
+		"typ":        {Data: []byte("x\n")},
+	}
+	newFS := fstest.MapFS{
+		"target.txt": {Data: []byte("hi\n")},
+		"link":       {Data: []byte("../outside"), Mode: fs.ModeSymlink},
+		"new":        {Data: []byte("target.txt"), Mode: fs.ModeSymlink},
+		"typ":        {Data: []byte("target.txt"), Mode: fs.ModeSymlink},
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "symlink", "input.diff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := DiffFS(oldFS, newFS, nil)
+	if err != nil {
+		t.Fatalf("DiffFS returned error: %v", err)
+	}
+	if got := diff.String(); got != string(want) {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,265 @@
+package godiffy
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	abbrevLength = 7
+	// binaryCheckLength matches git, which looks for a NUL byte in the first
+	// 8000 bytes of a file to decide whether it is binary.
+	binaryCheckLength = 8000
+)
+
+type treeFile struct {
+	path  string
+	mode  FileMode
+	data  []byte
+	hash  ObjectID
+	spans map[uint32]int
+}
+
+func (f *treeFile) isRegular() bool {
+	return f.mode.IsRegular()
+}
+
+// readLinkFS is the part of fs.ReadLinkFS that readTree needs to include
+// symbolic links, which are skipped in file systems without it.
+type readLinkFS interface {
+	ReadLink(name string) (string, error)
+}
+
+func DefaultTreeDiffOptions() *TreeDiffOptions {
+	return &TreeDiffOptions{
+		DiffOptions:     *DefaultDiffOptions(),
+		DetectRenames:   true,
+		RenameThreshold: DefaultRenameThreshold,
+	}
+}
+
+// DiffFS compares two file trees the way "git diff --no-index" does and
+// returns a FileDiff for every added, deleted, modified, renamed or copied
+// file. A nil opts uses DefaultTreeDiffOptions.
+func DiffFS(oldFS, newFS fs.FS, opts *TreeDiffOptions) (*Diff, error) {
+	if opts == nil {
+		opts = DefaultTreeDiffOptions()
+	}
+	if opts.RenameThreshold < 0 || opts.RenameThreshold > 100 {
+		return nil, fmt.Errorf("invalid rename threshold: %d", opts.RenameThreshold)
+	}
+
+	oldFiles, err := readTree(oldFS, opts.ObjectFormat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read old tree: %w", err)
+	}
+	newFiles, err := readTree(newFS, opts.ObjectFormat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read new tree: %w", err)
+	}
+
+	paths := make([]string, 0, len(oldFiles)+len(newFiles))
+	for p := range oldFiles {
+		paths = append(paths, p)
+	}
+	for p := range newFiles {
+		if _, ok := oldFiles[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	slices.SortFunc(paths, comparePaths)
+
+	// Sources are the files a rename or copy may come from: deleted files,
+	// plus modified ones when looking for copies.
+	var sources, dests []*treeFile
+	var deleted []bool
+	for _, p := range paths {
+		oldFile, inOld := oldFiles[p]
+		newFile, inNew := newFiles[p]
+		switch {
+		case !inNew:
+			sources = append(sources, oldFile)
+			deleted = append(deleted, true)
+		case !inOld:
+			dests = append(dests, newFile)
+		case opts.DetectCopies && (oldFile.hash != newFile.hash || oldFile.mode != newFile.mode):
+			sources = append(sources, oldFile)
+			deleted = append(deleted, false)
+		}
+	}
+
+	match := make([]int, len(dests))
+	scores := make([]int, len(dests))
+	for i := range match {
+		match[i] = -1
+	}
+	if opts.DetectRenames || opts.DetectCopies {
+		match, scores = detectRenames(sources, deleted, dests, opts)
+	}
+
+	// A deleted source used more than once is renamed into the last
+	// destination and copied into the others, as git reports it.
+	uses := make([]int, len(sources))
+	for _, s := range match {
+		if s != -1 {
+			uses[s]++
+		}
+	}
+	pairedAway := make([]bool, len(sources))
+	for s := range sources {
+		pairedAway[s] = deleted[s] && uses[s] > 0
+	}
+	sourceIndex := make(map[string]int, len(sources))
+	for i, src := range sources {
+		sourceIndex[src.path] = i
+	}
+	destIndex := make(map[string]int, len(dests))
+	for i, dst := range dests {
+		destIndex[dst.path] = i
+	}
+
+	result := &Diff{}
+	for _, p := range paths {
+		oldFile, inOld := oldFiles[p]
+		newFile, inNew := newFiles[p]
+		var file *FileDiff
+		switch {
+		case !inNew:
+			if pairedAway[sourceIndex[p]] {
+				continue
+			}
+			file, err = newTreeFileDiff(oldFile, nil, FileStatusDeleted, 0, opts)
+		case !inOld:
+			d := destIndex[p]
+			if s := match[d]; s != -1 {
+				status := FileStatusCopied
+				if deleted[s] {
+					uses[s]--
+					if uses[s] == 0 {
+						status = FileStatusRenamed
+					}
+				}
+				file, err = newTreeFileDiff(sources[s], newFile, status, scores[d], opts)
+			} else {
+				file, err = newTreeFileDiff(nil, newFile, FileStatusNew, 0, opts)
+			}
+		case oldFile.hash == newFile.hash && oldFile.mode == newFile.mode:
+			continue
+		case oldFile.mode.IsSymlink() != newFile.mode.IsSymlink():
+			// Git shows a change between a file and a link as a deletion
+			// followed by a creation.
+			var deletion *FileDiff
+			deletion, err = newTreeFileDiff(oldFile, nil, FileStatusDeleted, 0, opts)
+			if err != nil {
+				return nil, err
+			}
+			result.Files = append(result.Files, deletion)
+			file, err = newTreeFileDiff(nil, newFile, FileStatusNew, 0, opts)
+		default:
+			file, err = newTreeFileDiff(oldFile, newFile, FileStatusModified, 0, opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, file)
+	}
+	return result, nil
+}
+
+func readTree(fsys fs.FS, format ObjectFormat) (map[string]*treeFile, error) {
+	files := make(map[string]*treeFile)
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			linkFS, ok := fsys.(readLinkFS)
+			if !ok {
+				return nil
+			}
+			target, err := linkFS.ReadLink(p)
+			if err != nil {
+				return err
+			}
+			data := []byte(target)
+			files[p] = &treeFile{path: p, mode: FileModeSymlink, data: data, hash: BlobID(data, format)}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		mode := FileModeRegular
+		if info.Mode()&0111 != 0 {
+			mode = FileModeExecutable
+		}
+		files[p] = &treeFile{path: p, mode: mode, data: data, hash: BlobID(data, format)}
+		return nil
+	})
+	return files, err
+}
+
+func newTreeFileDiff(oldFile, newFile *treeFile, status FileStatus, score int, opts *TreeDiffOptions) (*FileDiff, error) {
+	file := &FileDiff{Status: status}
+	var oldData, newData []byte
+	oldHash, newHash := NullObjectID(opts.ObjectFormat), NullObjectID(opts.ObjectFormat)
+	if oldFile != nil {
+		file.OldPath, file.OldMode = oldFile.path, oldFile.mode
+		oldData, oldHash = oldFile.data, oldFile.hash
+	}
+	if newFile != nil {
+		file.NewPath, file.NewMode = newFile.path, newFile.mode
+		newData, newHash = newFile.data, newFile.hash
+	}
+	if oldFile == nil {
+		file.OldPath = file.NewPath
+	}
+	if newFile == nil {
+		file.NewPath = file.OldPath
+	}
+	if status == FileStatusRenamed || status == FileStatusCopied {
+		file.OldName, file.NewName = file.OldPath, file.NewPath
+		file.SimilarityIndex = strconv.Itoa(score*100/maxSimilarityScore) + "%"
+	}
+	file.Header = file.headerLine()
+
+	if oldHash == newHash {
+		return file, nil
+	}
+	file.OldHash, file.NewHash = oldHash, newHash
+	if !opts.FullIndex {
+		file.OldHash, file.NewHash = file.OldHash.Abbrev(abbrevLength), file.NewHash.Abbrev(abbrevLength)
+	}
+
+	if isBinary(oldData) || isBinary(newData) {
+		file.IsBinary = true
+		return file, nil
+	}
+	computed, err := Compute(string(oldData), string(newData), &opts.DiffOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s: %w", file.NewPath, err)
+	}
+	file.Hunks = computed.Hunks
+	return file, nil
+}
+
+// comparePaths orders paths the way a directory walk visits them, comparing
+// one path component at a time.
+func comparePaths(a, b string) int {
+	return slices.Compare(strings.Split(a, "/"), strings.Split(b, "/"))
+}
+
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), binaryCheckLength)], 0) != -1
+}
@@ -0,0 +1,219 @@
+package godiffy
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func testTrees() (fstest.MapFS, fstest.MapFS) {
+	var big, edited string
+	for _, line := range []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15", "16", "17", "18", "19", "20"} {
+		big += line + " line of text\n"
+		switch line {
+		case "5":
+			edited += "five\n"
+		case "9":
+			edited += "nine\n"
+		default:
+			edited += line + " line of text\n"
+		}
+	}
+
+	oldFS := fstest.MapFS{
+		"keep.txt":    {Data: []byte("same\n")},
+		"mod.txt":     {Data: []byte("a\nb\nc\n")},
+		"gone.txt":    {Data: []byte("bye\n")},
+		"src/big.txt": {Data: []byte(big)},
+		"exact.txt":   {Data: []byte("exact content\n")},
+		"run.sh":      {Data: []byte("#!/bin/sh\n"), Mode: 0644},
+		"bin.dat":     {Data: []byte("a\x00b")},
+	}
+	newFS := fstest.MapFS{
+		"keep.txt":        {Data: []byte("same\n")},
+		"mod.txt":         {Data: []byte("a\nB\nc\n")},
+		"added.txt":       {Data: []byte("hello\n")},
+		"dst/big.txt":     {Data: []byte(edited)},
+		"moved/exact.txt": {Data: []byte("exact content\n")},
+		"run.sh":          {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+		"bin.dat":         {Data: []byte("a\x00c")},
+		"empty.txt":       {Data: []byte{}},
+	}
+	return oldFS, newFS
+}
+
+// The expected output is what "git diff --no-index -M" prints for the same
+// trees.
+func TestDiffFS(t *testing.T) {
+	oldFS, newFS := testTrees()
+	diff, err := DiffFS(oldFS, newFS, nil)
+	if err != nil {
+		t.Fatalf("DiffFS returned error: %v", err)
+	}
+
+	want := `diff --git a/added.txt b/added.txt
+new file mode 100644
+index 0000000..ce01362
+--- /dev/null
++++ b/added.txt
+@@ -0,0 +1 @@
++hello
+diff --git a/bin.dat b/bin.dat
+index 20b5be9..88f3700 100644
+Binary files a/bin.dat and b/bin.dat differ
+diff --git a/src/big.txt b/dst/big.txt
+similarity index 90%
+rename from src/big.txt
+rename to dst/big.txt
+index f94eba4..a251b60 100644
+--- a/src/big.txt
++++ b/dst/big.txt
+@@ -2,11 +2,11 @@
+ 2 line of text
+ 3 line of text
+ 4 line of text
+-5 line of text
++five
+ 6 line of text
+ 7 line of text
+ 8 line of text
+-9 line of text
++nine
+ 10 line of text
+ 11 line of text
+ 12 line of text
+diff --git a/empty.txt b/empty.txt
+new file mode 100644
+index 0000000..e69de29
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+index b023018..0000000
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git a/mod.txt b/mod.txt
+index de98044..7be73ce 100644
+--- a/mod.txt
++++ b/mod.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+diff --git a/exact.txt b/moved/exact.txt
+similarity index 100%
+rename from exact.txt
+rename to moved/exact.txt
+diff --git a/run.sh b/run.sh
+old mode 100644
+new mode 100755
+`
+	if got := diff.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDiffFSStatuses(t *testing.T) {
+	oldFS, newFS := testTrees()
+	diff, err := DiffFS(oldFS, newFS, nil)
+	if err != nil {
+		t.Fatalf("DiffFS returned error: %v", err)
+	}
+
+	want := map[string]FileStatus{
+		"added.txt":       FileStatusNew,
+		"bin.dat":         FileStatusModified,
+		"dst/big.txt":     FileStatusRenamed,
+		"empty.txt":       FileStatusNew,
+		"gone.txt":        FileStatusDeleted,
+		"mod.txt":         FileStatusModified,
+		"moved/exact.txt": FileStatusRenamed,
+		"run.sh":          FileStatusModified,
+	}
+	if len(diff.Files) != len(want) {
+		t.Fatalf("expected %d files, got %d", len(want), len(diff.Files))
+	}
+	for _, file := range diff.Files {
+		if status, ok := want[file.NewPath]; !ok || status != file.Status {
+			t.Errorf("file %s: status = %d, want %d", file.NewPath, file.Status, status)
+		}
+	}
+	if !diff.Files[1].IsBinary {
+		t.Errorf("expected bin.dat to be binary")
+	}
+}
+
+func TestDiffFSRenameThreshold(t *testing.T) {
+	oldFS, newFS := testTrees()
+	opts := DefaultTreeDiffOptions()
+	opts.RenameThreshold = 95
+	diff, err := DiffFS(oldFS, newFS, opts)
+	if err != nil {
+		t.Fatalf("DiffFS returned error: %v", err)
+	}
+
+	for _, file := range diff.Files {
+		switch file.NewPath {
+		case "dst/big.txt":
+			if file.Status != FileStatusNew {
+				t.Errorf("expected 90%% similar file to be new at 95%% threshold, got status %d", file.Status)
+			}
+		case "src/big.txt":
+			if file.Status != FileStatusDeleted {
+				t.Errorf("expected source to be deleted, got status %d", file.Status)
+			}
+		case "moved/exact.txt":
+			if file.Status != FileStatusRenamed || file.SimilarityIndex != "100%" {
+				t.Errorf("expected exact rename, got status %d similarity %s", file.Status, file.SimilarityIndex)
+			}
+		}
+	}
+}
+
+func TestDiffFSCopies(t *testing.T) {
+	oldFS := fstest.MapFS{
+		"base.txt": {Data: []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")},
+	}
+	newFS := fstest.MapFS{
+		"base.txt": {Data: []byte("1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n")},
+		"copy.txt": {Data: []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\nten\n")},
+	}
+	opts := DefaultTreeDiffOptions()
+	opts.DetectCopies = true
+	diff, err := DiffFS(oldFS, newFS, opts)
+	if err != nil {
+		t.Fatalf("DiffFS returned error: %v", err)
+	}
+
+	if len(diff.Files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(diff.Files))
+	}
+	copied := diff.Files[1]
+	if copied.Status != FileStatusCopied || copied.OldPath != "base.txt" || copied.NewPath != "copy.txt" {
+		t.Errorf("expected copy from base.txt to copy.txt, got status %d %s -> %s", copied.Status, copied.OldPath, copied.NewPath)
+	}
+}
+
+func TestDiffFSParseRoundTrip(t *testing.T) {
+	oldFS, newFS := testTrees()
+	diff, err := DiffFS(oldFS, newFS, nil)
+	if err != nil {
+		t.Fatalf("DiffFS returned error: %v", err)
+	}
+
+	parsed, err := Parse(diff.String())
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if got, want := parsed.String(), diff.String(); got != want {
+		t.Errorf("round trip =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDiffFSInvalidThreshold(t *testing.T) {
+	opts := DefaultTreeDiffOptions()
+	opts.RenameThreshold = 101
+	if _, err := DiffFS(fstest.MapFS{}, fstest.MapFS{}, opts); err == nil {
+		t.Fatal("expected error for invalid threshold, got nil")
+	}
+}
@@ -0,0 +1,157 @@
+package gomergy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+// applyHunks applies the hunks to content in order, matching lines exactly.
+func applyHunks(content string, hunks []*godiffy.Hunk) (string, error) {
+	return applyHunksWithOptions(content, hunks, nil)
+}
+
+// applyHunksWithOptions applies the hunks to content in order and fails on
+// the first hunk that does not match.
+func applyHunksWithOptions(content string, hunks []*godiffy.Hunk, opts *Options) (string, error) {
+	result, results := applyMatchingHunks(content, hunks, opts)
+	if err := hunkError(hunks, results); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// hunkError reports the first hunk that did not apply, if any.
+func hunkError(hunks []*godiffy.Hunk, results []HunkResult) error {
+	if failed := rejectedHunks(results); len(failed) > 0 {
+		return fmt.Errorf("hunk #%d does not apply at line %d", failed[0]+1, hunks[failed[0]].OldStart)
+	}
+	return nil
+}
+
+// rejectedHunks returns the indexes of the hunks that did not apply.
+func rejectedHunks(results []HunkResult) []int {
+	var failed []int
+	for i, result := range results {
+		if !result.Applied {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// applyMatchingHunks applies the hunks to content in order and returns how
+// each went. Hunks that do not match are left out. Each hunk is
+// looked for at its line number, shifted by the offset the previous hunks
+// were found at, and then at increasing distances from there, like git
+// apply does.
+func applyMatchingHunks(content string, hunks []*godiffy.Hunk, opts *Options) (string, []HunkResult) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	results := make([]HunkResult, len(hunks))
+	lines := splitLines(content)
+	equal := opts.lineMatcher()
+	ending := fileLineEnding(lines)
+	var result strings.Builder
+	pos, offset := 0, 0
+	for i, hunk := range hunks {
+		preimage := hunkPreimage(hunk)
+		base := hunkIndex(hunk)
+		at, ok := findLines(lines, preimage, base+offset, pos, equal)
+		if !ok {
+			continue
+		}
+		results[i] = HunkResult{Applied: true, Line: at + 1, Offset: at - base}
+		for _, line := range lines[pos:at] {
+			result.WriteString(line)
+		}
+		writeHunk(&result, lines[at:at+len(preimage)], hunk, ending, opts)
+		pos, offset = at+len(preimage), at-base
+	}
+	for _, line := range lines[pos:] {
+		result.WriteString(line)
+	}
+	return result.String(), results
+}
+
+// writeHunk writes what hunk turns matched, the lines it was found at, into.
+// Context lines are copied from matched, so only deleted and added lines
+// come from the hunk.
+func writeHunk(result *strings.Builder, matched []string, hunk *godiffy.Hunk, ending string, opts *Options) {
+	next := 0
+	for _, line := range hunk.Lines {
+		switch line.Type {
+		case godiffy.HunkLineContext:
+			have := matched[next]
+			// A last line without newline that now has lines after it.
+			if !strings.HasSuffix(have, "\n") && strings.HasSuffix(line.Content, "\n") {
+				have += ending
+			}
+			result.WriteString(have)
+			next++
+		case godiffy.HunkLineDeleted:
+			next++
+		case godiffy.HunkLineAdded:
+			result.WriteString(opts.addedLine(line.Content, ending))
+		}
+	}
+}
+
+// hunkPreimage returns the lines a hunk expects to find: its context and
+// deleted lines.
+func hunkPreimage(hunk *godiffy.Hunk) []string {
+	var preimage []string
+	for _, line := range hunk.Lines {
+		if line.Type != godiffy.HunkLineAdded {
+			preimage = append(preimage, line.Content)
+		}
+	}
+	return preimage
+}
+
+// hunkIndex returns the 0-based index of the first line a hunk replaces. An
+// empty old range names the line before it.
+func hunkIndex(hunk *godiffy.Hunk) int {
+	if hunk.OldLineCount == 0 {
+		return max(hunk.OldStart, 0)
+	}
+	return max(hunk.OldStart-1, 0)
+}
+
+// findLines searches lines for want, starting at index expected and moving
+// outwards, without going before limit.
+func findLines(lines, want []string, expected, limit int, equal func(have, want string) bool) (int, bool) {
+	last := len(lines) - len(want)
+	if last < limit {
+		return 0, false
+	}
+	expected = min(max(expected, limit), last)
+	for distance := 0; expected-distance >= limit || expected+distance <= last; distance++ {
+		if at := expected - distance; at >= limit && matchLines(lines[at:], want, equal) {
+			return at, true
+		}
+		if at := expected + distance; distance > 0 && at <= last && matchLines(lines[at:], want, equal) {
+			return at, true
+		}
+	}
+	return 0, false
+}
+
+func matchLines(lines, want []string, equal func(have, want string) bool) bool {
+	for i, line := range want {
+		if !equal(lines[i], line) {
+			return false
+		}
+	}
+	return true
+}
+
+func splitLines(text string) []string {
+	var lines []string
+	for line := range strings.Lines(text) {
+		lines = append(lines, line)
+	}
+	return lines
+}
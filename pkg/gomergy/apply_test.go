@@ -0,0 +1,78 @@
+package gomergy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+func TestApplyHunks(t *testing.T) {
+	tests := []struct {
+		name      string
+		old, new  string
+		target    string
+		want      string
+		wantError bool
+	}{
+		{
+			name:   "exact position",
+			old:    "a\nb\nc\nd\ne\n",
+			new:    "a\nb\nC\nd\ne\n",
+			target: "a\nb\nc\nd\ne\n",
+			want:   "a\nb\nC\nd\ne\n",
+		},
+		{
+			name:   "shifted by inserted lines",
+			old:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			new:    "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n",
+			target: "0\n0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			want:   "0\n0\n1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n",
+		},
+		{
+			name:   "missing newline",
+			old:    "a\nb",
+			new:    "a\nb\nc\n",
+			target: "a\nb",
+			want:   "a\nb\nc\n",
+		},
+		{
+			name:   "empty file",
+			old:    "",
+			new:    "x\n",
+			target: "",
+			want:   "x\n",
+		},
+		{
+			name:      "context mismatch",
+			old:       "a\nb\nc\n",
+			new:       "a\nB\nc\n",
+			target:    "a\nx\nc\n",
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := godiffy.Compute(tt.old, tt.new, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := applyHunks(tt.target, file.Hunks)
+			if tt.wantError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), "hunk #1 does not apply") {
+					t.Errorf("unexpected error message: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected success, got %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("content = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
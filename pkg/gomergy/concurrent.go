@@ -0,0 +1,154 @@
+package gomergy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"sync"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+// MergeToPathContext is MergeToPathWithOptions applying opts.Workers files
+// at once. Files that share a path, or whose paths are inside one another,
+// are applied in order by the same worker, so renames, copies and files
+// replacing directories still see the tree they expect. An error in one
+// file skips the files that depend on it but not the others, and the
+// errors of all files are returned joined in the order of the diff.
+// Cancelling ctx stops the workers before their next file and adds
+// ctx.Err() in front of the errors; files applied by then stay applied.
+func MergeToPathContext(ctx context.Context, diff *godiffy.Diff, path string, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if _, err := os.ReadDir(path); err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", path, err)
+	}
+	diff, err := prepareDiff(diff, opts)
+	if err != nil {
+		return err
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	groups := fileGroups(diff.Files)
+	errs := make([]error, len(diff.Files))
+	queue := make(chan []int)
+	var wg sync.WaitGroup
+	for range min(workers, len(groups)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range queue {
+				for _, i := range group {
+					if ctx.Err() != nil {
+						break
+					}
+					errs[i] = mergeFile(diff.Files[i], path, opts)
+					var reject *FileReject
+					if errs[i] != nil && !errors.As(errs[i], &reject) {
+						break
+					}
+				}
+			}
+		}()
+	}
+dispatch:
+	for _, group := range groups {
+		select {
+		case queue <- group:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	var failed []error
+	if err := ctx.Err(); err != nil {
+		failed = append(failed, err)
+	}
+	var rejects []*FileReject
+	for _, err := range errs {
+		var reject *FileReject
+		if errors.As(err, &reject) {
+			rejects = append(rejects, reject)
+		} else if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if len(rejects) > 0 {
+		failed = append(failed, &RejectError{Files: rejects})
+	}
+	return errors.Join(failed...)
+}
+
+// fileGroups splits the indexes of files into groups that can be applied
+// independently: files are in the same group when they name the same path
+// or one's path is inside the other's. Groups and the indexes in them are
+// in the order of files.
+func fileGroups(files []*godiffy.FileDiff) [][]int {
+	parent := make([]int, len(files))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		i, j = find(i), find(j)
+		parent[max(i, j)] = min(i, j)
+	}
+
+	owners := make(map[string]int)
+	for i, file := range files {
+		for _, name := range filePaths(file) {
+			if j, ok := owners[name]; ok {
+				union(i, j)
+			} else {
+				owners[name] = i
+			}
+		}
+	}
+	for i, file := range files {
+		for _, name := range filePaths(file) {
+			for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+				if j, ok := owners[dir]; ok {
+					union(i, j)
+				}
+			}
+		}
+	}
+
+	var groups [][]int
+	index := make(map[int]int)
+	for i := range files {
+		root := find(i)
+		g, ok := index[root]
+		if !ok {
+			g = len(groups)
+			index[root] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
+
+// filePaths returns the paths below the tree that applying file touches.
+func filePaths(file *godiffy.FileDiff) []string {
+	names := []string{file.NewPath}
+	if source := sourcePath(file); source != "" && source != file.NewPath {
+		names = append(names, source)
+	}
+	return names
+}
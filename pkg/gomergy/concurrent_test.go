@@ -0,0 +1,188 @@
+package gomergy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+func TestMergeToPathContext(t *testing.T) {
+	oldFS, newFS := fstest.MapFS{}, fstest.MapFS{}
+	before, after := map[string]string{}, map[string]string{}
+	for i := range 200 {
+		name := fmt.Sprintf("f%03d.txt", i)
+		before[name] = fmt.Sprintf("a\nb %d\nc\n", i)
+		after[name] = fmt.Sprintf("a\nB %d\nc\n", i)
+		oldFS[name] = &fstest.MapFile{Data: []byte(before[name]), Mode: 0644}
+		newFS[name] = &fstest.MapFile{Data: []byte(after[name]), Mode: 0644}
+	}
+	diff, err := godiffy.DiffFS(oldFS, newFS, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeTree(t, dir, before)
+
+	err = MergeToPathContext(context.Background(), diff, dir, &Options{Workers: 8, VerifyHashes: true})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	checkTree(t, dir, after)
+}
+
+// A rename, a new file at its old path and a change to the renamed file
+// only work in order.
+const dependentTestDiff = `diff --git a/a.txt b/b.txt
+similarity index 100%
+rename from a.txt
+rename to b.txt
+diff --git a/a.txt b/a.txt
+new file mode 100644
+--- /dev/null
++++ b/a.txt
+@@ -0,0 +1 @@
++new a
+diff --git a/b.txt b/b.txt
+--- a/b.txt
++++ b/b.txt
+@@ -1 +1 @@
+-old a
++old a, changed
+diff --git a/c.txt b/c.txt
+--- a/c.txt
++++ b/c.txt
+@@ -1 +1 @@
+-c
++C
+`
+
+func TestMergeToPathContext_DependentFiles(t *testing.T) {
+	diff, err := godiffy.Parse(dependentTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 20 {
+		dir := t.TempDir()
+		writeTree(t, dir, map[string]string{"a.txt": "old a\n", "c.txt": "c\n"})
+		err := MergeToPathContext(context.Background(), diff, dir, &Options{Workers: 4})
+		if err != nil {
+			t.Fatalf("expected success, got %v", err)
+		}
+		checkTree(t, dir, map[string]string{"a.txt": "new a\n", "b.txt": "old a, changed\n", "c.txt": "C\n"})
+	}
+}
+
+func TestMergeToPathContext_ErrorsInOrder(t *testing.T) {
+	diff, err := godiffy.Parse(dependentTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 20 {
+		dir := t.TempDir()
+		// The rename fails, which skips the files after it; c.txt does not
+		// match.
+		writeTree(t, dir, map[string]string{"c.txt": "x\n"})
+		err := MergeToPathContext(context.Background(), diff, dir, &Options{Workers: 4})
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		lines := strings.Split(err.Error(), "\n")
+		if len(lines) != 2 || !strings.Contains(lines[0], "renamed file b.txt") || !strings.Contains(lines[1], "modified file c.txt") {
+			t.Fatalf("unexpected errors:\n%v", err)
+		}
+		checkTree(t, dir, map[string]string{"c.txt": "x\n"})
+	}
+}
+
+func TestMergeToPathContext_Rejects(t *testing.T) {
+	diff, err := godiffy.Parse(rejectTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": numberLines(map[int]string{2: "two"}), "b.txt": "x\ny\n"})
+
+	err = MergeToPathContext(context.Background(), diff, dir, &Options{Reject: true})
+	var rejectErr *RejectError
+	if !errors.As(err, &rejectErr) || len(rejectErr.Files) != 1 || rejectErr.Files[0].Path != "a.txt" {
+		t.Fatalf("expected a RejectError for a.txt, got %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "b.txt")); err != nil || string(data) != "x\nY\n" {
+		t.Errorf("b.txt = %q; want %q", data, "x\nY\n")
+	}
+}
+
+func TestMergeToPathContext_Cancelled(t *testing.T) {
+	diff, err := godiffy.Parse(dependentTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "old a\n", "c.txt": "c\n"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = MergeToPathContext(ctx, diff, dir, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	checkTree(t, dir, map[string]string{"a.txt": "old a\n", "c.txt": "c\n"})
+}
+
+// cancelAfterCtx is cancelled once Err has been asked n times, so that a
+// test can stop MergeToPathContext between two files.
+type cancelAfterCtx struct {
+	context.Context
+	cancel context.CancelFunc
+	n      atomic.Int32
+}
+
+func (c *cancelAfterCtx) Err() error {
+	if c.n.Add(-1) < 0 {
+		c.cancel()
+	}
+	return c.Context.Err()
+}
+
+func TestMergeToPathContext_CancelledKeepsErrors(t *testing.T) {
+	diff, err := godiffy.Parse("diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+A\n" +
+		"diff --git a/c.txt b/c.txt\n--- a/c.txt\n+++ b/c.txt\n@@ -1 +1 @@\n-c\n+C\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "not a\n", "c.txt": "c\n"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelling := &cancelAfterCtx{Context: ctx, cancel: cancel}
+	cancelling.n.Store(1) // a.txt is tried, c.txt is not
+
+	err = MergeToPathContext(cancelling, diff, dir, &Options{Workers: 1})
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "a.txt") {
+		t.Fatalf("expected context.Canceled and the error of a.txt, got %v", err)
+	}
+	checkTree(t, dir, map[string]string{"a.txt": "not a\n", "c.txt": "c\n"})
+}
+
+func TestFileGroups(t *testing.T) {
+	files := []*godiffy.FileDiff{
+		{NewPath: "a"},
+		{NewPath: "x/y.txt"},
+		{NewPath: "a/b.txt"},
+		{NewPath: "z.txt", OldPath: "x/y.txt", Status: godiffy.FileStatusRenamed},
+		{NewPath: "q.txt"},
+		{NewPath: "x"},
+	}
+	got := fmt.Sprint(fileGroups(files))
+	if want := "[[0 2] [1 3 5] [4]]"; got != want {
+		t.Errorf("fileGroups = %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,29 @@
+package gomergy
+
+const (
+	LineEndingKeep          LineEndingMode = iota // lines must match with their endings, which are kept as they are
+	LineEndingIgnore                              // lines match whatever their endings, added lines take the file's endings
+	LineEndingLF                                  // like LineEndingIgnore, then every line ends in "\n"
+	LineEndingCRLF                                // like LineEndingIgnore, then every line ends in "\r\n"
+	LineEndingAutoCRLF                            // like core.autocrlf=true: files are patched as LF and written as CRLF
+	LineEndingAutoCRLFInput                       // like core.autocrlf=input: files are patched and written as LF
+)
+
+const (
+	WhitespaceActionNoWarn WhitespaceAction = iota // apply added lines as they are
+	WhitespaceActionWarn                           // write whitespace errors to Options.Warnings and apply anyway
+	WhitespaceActionError                          // refuse to apply a diff with whitespace errors
+	WhitespaceActionFix                            // fix the errors in added lines, then apply
+)
+
+const (
+	WhitespaceTrailingSpace  WhitespaceErrorKind = iota // spaces or tabs at the end of a line
+	WhitespaceSpaceBeforeTab                            // a space before a tab in the indent
+	WhitespaceBlankAtEOF                                // blank lines added at the end of a file
+)
+
+const (
+	ConflictStyleMerge  ConflictStyle = iota // ours and theirs between markers, like git's default
+	ConflictStyleDiff3                       // ours, base and theirs, like merge.conflictStyle=diff3
+	ConflictStyleZdiff3                      // like ConflictStyleDiff3 with lines common to both sides moved out of the conflict
+)
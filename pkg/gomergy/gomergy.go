@@ -1,43 +1,197 @@
 package gomergy
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strconv"
 
 	"github.com/asdfgugus/godiffy/pkg/godiffy"
 )
 
+// MergeToPath applies diff to the files below path with default options.
 func MergeToPath(diff *godiffy.Diff, path string) error {
+	return MergeToPathWithOptions(diff, path, nil)
+}
+
+// MergeToPathWithOptions applies diff to the files below path. A nil opts
+// uses the zero Options. With opts.Reject, hunks that do not apply are
+// saved to reject files and reported in a *RejectError at the end.
+func MergeToPathWithOptions(diff *godiffy.Diff, path string, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
 	if _, err := os.ReadDir(path); err != nil {
 		return fmt.Errorf("failed to read directory %s: %w", path, err)
 	}
+	diff, err := prepareDiff(diff, opts)
+	if err != nil {
+		return err
+	}
 
+	var rejects []*FileReject
 	for _, file := range diff.Files {
-		switch file.Status {
-		case godiffy.FileStatusDeleted:
-			err := handleDeletedFile(file, path)
-			if err != nil {
-				return fmt.Errorf("failed to handle deleted file %s: %w", file.NewPath, err)
-			}
-		case godiffy.FileStatusNew:
-			err := handleNewFile(file, path)
-			if err != nil {
-				return fmt.Errorf("failed to handle new file %s: %w", file.NewPath, err)
+		err := mergeFile(file, path, opts)
+		// A partly applied file cannot match its postimage, so it is only
+		// reported once the rest of the diff is applied.
+		var reject *FileReject
+		if errors.As(err, &reject) {
+			rejects = append(rejects, reject)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(rejects) > 0 {
+		return &RejectError{Files: rejects}
+	}
+	return nil
+}
+
+// mergeFile applies one file of a diff below path. A file with rejected
+// hunks returns an error wrapping its *FileReject.
+func mergeFile(file *godiffy.FileDiff, path string, opts *Options) error {
+	for _, name := range filePaths(file) {
+		if err := checkPath(name, path); err != nil {
+			return err
+		}
+	}
+	if file.IsSubmodule() {
+		err := handleSubmodule(file, path)
+		if err != nil {
+			return fmt.Errorf("failed to handle submodule %s: %w", file.NewPath, err)
+		}
+		return nil
+	}
+	if opts.VerifyHashes {
+		if err := verifyPreimage(file, path, opts); err != nil {
+			return err
+		}
+		if err := verifyPostimage(file, path, opts); err != nil {
+			return err
+		}
+	}
+	var err error
+	switch file.Status {
+	case godiffy.FileStatusDeleted:
+		if err = handleDeletedFile(file, path); err != nil {
+			err = fmt.Errorf("failed to handle deleted file %s: %w", file.NewPath, err)
+		}
+	case godiffy.FileStatusNew:
+		if err = handleNewFile(file, path, opts); err != nil {
+			err = fmt.Errorf("failed to handle new file %s: %w", file.NewPath, err)
+		}
+	case godiffy.FileStatusModified:
+		if err = handleModifiedFile(file, path, opts); err != nil {
+			err = fmt.Errorf("failed to handle modified file %s: %w", file.NewPath, err)
+		}
+	case godiffy.FileStatusRenamed:
+		if err = handleRenamedFile(file, path, opts); err != nil {
+			err = fmt.Errorf("failed to handle renamed file %s: %w", file.NewPath, err)
+		}
+	case godiffy.FileStatusCopied:
+		if err = handleCopiedFile(file, path, opts); err != nil {
+			err = fmt.Errorf("failed to handle copied file %s: %w", file.NewPath, err)
+		}
+	}
+	return err
+}
+
+// prepareDiff turns diff into the one to apply: reversed if opts asks for
+// it, and with its whitespace errors reported or fixed.
+func prepareDiff(diff *godiffy.Diff, opts *Options) (*godiffy.Diff, error) {
+	if opts.Reverse {
+		diff = diff.Reverse()
+	}
+	switch opts.Whitespace {
+	case WhitespaceActionWarn, WhitespaceActionFix:
+		for _, wsErr := range CheckWhitespace(diff) {
+			opts.warn(wsErr.Error())
+		}
+		if opts.Whitespace == WhitespaceActionFix {
+			diff = fixWhitespace(diff)
+		}
+	case WhitespaceActionError:
+		if wsErrs := CheckWhitespace(diff); len(wsErrs) > 0 {
+			errs := make([]error, len(wsErrs))
+			for i, wsErr := range wsErrs {
+				errs[i] = wsErr
 			}
-		case godiffy.FileStatusModified:
-			err := handleModifiedFile(file, path)
-			if err != nil {
-				return fmt.Errorf("failed to handle modified file %s: %w", file.NewPath, err)
+			return nil, fmt.Errorf("found %d whitespace errors:\n%w", len(wsErrs), errors.Join(errs...))
+		}
+	}
+	return diff, nil
+}
+
+// verifyPreimage checks that the file about to be patched is the one the
+// diff was made against. Files without a blob ID are not checked, and line
+// endings are converted first as they are for patching.
+func verifyPreimage(file *godiffy.FileDiff, path string, opts *Options) error {
+	if file.Status == godiffy.FileStatusNew || file.OldHash == "" || file.OldHash.IsNull() {
+		return nil
+	}
+	preimage := file.NewPath
+	if file.Status == godiffy.FileStatusRenamed || file.Status == godiffy.FileStatusCopied {
+		preimage = sourcePath(file)
+	}
+	data, err := readBlob(filepath.Join(path, preimage), opts)
+	if err != nil {
+		return fmt.Errorf("failed to read preimage %s: %w", preimage, err)
+	}
+	return checkBlob("preimage", preimage, file.OldHash, data)
+}
+
+// verifyPostimage checks that patching will produce the file the diff
+// describes, before anything is written. Hunks that do not apply are left
+// to the handlers to report or reject, and then nothing is checked.
+func verifyPostimage(file *godiffy.FileDiff, path string, opts *Options) error {
+	if file.Status == godiffy.FileStatusDeleted || file.NewHash == "" || file.NewHash.IsNull() {
+		return nil
+	}
+	preimage := file.NewPath
+	if file.Status == godiffy.FileStatusRenamed || file.Status == godiffy.FileStatusCopied {
+		preimage = sourcePath(file)
+	}
+	var original []byte
+	err := os.ErrNotExist
+	if file.Status != godiffy.FileStatusNew {
+		original, err = readBlob(filepath.Join(path, preimage), opts)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read preimage %s: %w", preimage, err)
+		}
+	}
+
+	// Without a file to patch, the result is rebuilt from the hunks alone.
+	var content string
+	if err == nil {
+		var results []HunkResult
+		content, results = applyMatchingHunks(string(original), file.Hunks, opts)
+		if hunkError(file.Hunks, results) != nil {
+			return nil
+		}
+	} else {
+		for _, hunk := range file.Hunks {
+			for _, line := range hunk.Lines {
+				if line.Type == godiffy.HunkLineAdded || line.Type == godiffy.HunkLineContext {
+					content += line.Content
+				}
 			}
 		}
 	}
+	return checkBlob("postimage", file.NewPath, file.NewHash, []byte(content))
+}
+
+// checkBlob checks data against hash, naming the side of the diff it is.
+func checkBlob(side, name string, hash godiffy.ObjectID, data []byte) error {
+	if !hash.MatchBlob(data) {
+		return fmt.Errorf("%s mismatch for %s: expected blob %s", side, name, hash)
+	}
 	return nil
 }
 
 func handleDeletedFile(file *godiffy.FileDiff, path string) error {
-	if _, err := os.Open(filepath.Join(path, file.NewPath)); os.IsNotExist(err) {
+	if _, err := os.Lstat(filepath.Join(path, file.NewPath)); os.IsNotExist(err) {
 		return nil
 	}
 
@@ -49,23 +203,35 @@ func handleDeletedFile(file *godiffy.FileDiff, path string) error {
 	return nil
 }
 
-func handleNewFile(file *godiffy.FileDiff, path string) error {
+func handleNewFile(file *godiffy.FileDiff, path string, opts *Options) error {
+	if file.NewMode.IsSymlink() {
+		_, target := file.LinkTargets()
+		return writeSymlink(file.NewPath, target, path)
+	}
+
 	err := os.MkdirAll(filepath.Dir(filepath.Join(path, file.NewPath)), 0755)
 	if err != nil {
 		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(filepath.Join(path, file.NewPath)), err)
 	}
 
+	// Writing through a link would change a file the diff does not name.
+	if isSymlink(filepath.Join(path, file.NewPath)) {
+		if err := os.Remove(filepath.Join(path, file.NewPath)); err != nil {
+			return fmt.Errorf("failed to remove symlink %s: %w", file.NewPath, err)
+		}
+	}
+
 	content := ""
 	for _, hunk := range file.Hunks {
 		for _, line := range hunk.Lines {
 			content += line.Content
 		}
 	}
-	fileMode, err := strconv.ParseInt(file.NewMode, 8, 0)
+	fileMode, err := file.NewMode.OSMode()
 	if err != nil {
 		return fmt.Errorf("failed to convert file mode %s: %w", file.NewMode, err)
 	}
-	err = os.WriteFile(filepath.Join(path, file.NewPath), []byte(content), os.FileMode(fileMode))
+	err = os.WriteFile(filepath.Join(path, file.NewPath), []byte(opts.writeEndings(content)), fileMode)
 	if err != nil {
 		return fmt.Errorf("failed to write file %s: %w", file.NewPath, err)
 	}
@@ -73,28 +239,196 @@ func handleNewFile(file *godiffy.FileDiff, path string) error {
 	return nil
 }
 
-func handleModifiedFile(file *godiffy.FileDiff, path string) error {
+func handleModifiedFile(file *godiffy.FileDiff, path string, opts *Options) error {
+	if file.IsSymlink() || isSymlink(filepath.Join(path, file.NewPath)) {
+		return handleModifiedSymlink(file, path, opts)
+	}
+
 	err := os.MkdirAll(filepath.Dir(filepath.Join(path, file.NewPath)), 0755)
 	if err != nil {
 		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(filepath.Join(path, file.NewPath)), err)
 	}
 
+	// An empty mode keeps the mode of the existing file.
+	var fileMode os.FileMode
+	if file.NewMode != "" {
+		mode, err := file.NewMode.OSMode()
+		if err != nil {
+			return fmt.Errorf("failed to convert file mode %s: %w", file.NewMode, err)
+		}
+		fileMode = mode
+	}
+
+	original, err := os.ReadFile(filepath.Join(path, file.NewPath))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read file %s: %w", file.NewPath, err)
+	}
+	exists := err == nil
+
+	// Without a file to patch, the result is rebuilt from the hunks alone.
 	content := ""
-	for _, hunk := range file.Hunks {
-		for _, line := range hunk.Lines {
-			if line.Type == godiffy.HunkLineAdded || line.Type == godiffy.HunkLineContext {
-				content += line.Content
+	var failed []int
+	if exists {
+		if opts.Reject {
+			var results []HunkResult
+			content, results = applyMatchingHunks(opts.readEndings(string(original)), file.Hunks, opts)
+			failed = rejectedHunks(results)
+		} else {
+			content, err = applyHunksWithOptions(opts.readEndings(string(original)), file.Hunks, opts)
+			if err != nil {
+				return fmt.Errorf("failed to apply hunks to %s: %w", file.NewPath, err)
+			}
+		}
+		if file.NewMode == "" {
+			info, err := os.Stat(filepath.Join(path, file.NewPath))
+			if err != nil {
+				return fmt.Errorf("failed to stat file %s: %w", file.NewPath, err)
+			}
+			fileMode = info.Mode().Perm()
+		}
+	} else {
+		if file.NewMode == "" {
+			return fmt.Errorf("missing file mode for new content of %s", file.NewPath)
+		}
+		for _, hunk := range file.Hunks {
+			for _, line := range hunk.Lines {
+				if line.Type == godiffy.HunkLineAdded || line.Type == godiffy.HunkLineContext {
+					content += line.Content
+				}
 			}
 		}
 	}
-	fileMode, err := strconv.ParseInt(file.NewMode, 8, 0)
+
+	err = os.WriteFile(filepath.Join(path, file.NewPath), []byte(opts.writeEndings(content)), fileMode)
 	if err != nil {
-		return fmt.Errorf("failed to convert file mode %s: %w", file.NewMode, err)
+		return fmt.Errorf("failed to write file %s: %w", file.NewPath, err)
+	}
+	// WriteFile only applies the mode to files it creates.
+	if exists {
+		if err := os.Chmod(filepath.Join(path, file.NewPath), fileMode); err != nil {
+			return fmt.Errorf("failed to change mode of %s: %w", file.NewPath, err)
+		}
 	}
-	err = os.WriteFile(filepath.Join(path, file.NewPath), []byte(content), os.FileMode(fileMode))
+	if len(failed) > 0 {
+		return writeReject(file, failed, path)
+	}
+
+	return nil
+}
+
+func handleRenamedFile(file *godiffy.FileDiff, path string, opts *Options) error {
+	target, err := prepareTarget(file, path)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(filepath.Join(path, sourcePath(file)), target)
+	if err != nil {
+		return fmt.Errorf("failed to rename %s to %s: %w", sourcePath(file), file.NewPath, err)
+	}
+
+	return handleModifiedFile(file, path, opts)
+}
+
+func handleCopiedFile(file *godiffy.FileDiff, path string, opts *Options) error {
+	target, err := prepareTarget(file, path)
+	if err != nil {
+		return err
+	}
+	source := filepath.Join(path, sourcePath(file))
+	if isSymlink(source) {
+		linkTarget, err := os.Readlink(source)
+		if err != nil {
+			return fmt.Errorf("failed to read symlink %s: %w", sourcePath(file), err)
+		}
+		if err := os.Symlink(linkTarget, target); err != nil {
+			return fmt.Errorf("failed to create symlink %s: %w", file.NewPath, err)
+		}
+		return handleModifiedFile(file, path, opts)
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("failed to stat file %s: %w", sourcePath(file), err)
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", sourcePath(file), err)
+	}
+	err = os.WriteFile(target, data, info.Mode().Perm())
 	if err != nil {
 		return fmt.Errorf("failed to write file %s: %w", file.NewPath, err)
 	}
 
+	return handleModifiedFile(file, path, opts)
+}
+
+// handleSubmodule records a gitlink the way git apply does in a work tree:
+// the commit is not checked out, only the directory of the submodule is
+// created or removed. A gitlink that replaces a file, or the other way
+// around, is refused.
+func handleSubmodule(file *godiffy.FileDiff, path string) error {
+	if file.OldMode != "" && file.NewMode != "" && file.OldMode != file.NewMode {
+		return fmt.Errorf("cannot change %s between a file and a submodule", file.NewPath)
+	}
+	switch file.Status {
+	case godiffy.FileStatusNew:
+		return createSubmoduleDir(file.NewPath, path)
+	case godiffy.FileStatusDeleted:
+		return removeSubmoduleDir(file.NewPath, path)
+	case godiffy.FileStatusRenamed:
+		if err := removeSubmoduleDir(sourcePath(file), path); err != nil {
+			return err
+		}
+		return createSubmoduleDir(file.NewPath, path)
+	case godiffy.FileStatusCopied:
+		return createSubmoduleDir(file.NewPath, path)
+	}
+	return nil
+}
+
+// createSubmoduleDir creates the empty directory of a new submodule.
+func createSubmoduleDir(name, path string) error {
+	err := os.MkdirAll(filepath.Join(path, name), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", name, err)
+	}
+	return nil
+}
+
+// removeSubmoduleDir removes the directory of a submodule if it is empty.
+// A checked out submodule is left alone, as git does.
+func removeSubmoduleDir(name, path string) error {
+	entries, err := os.ReadDir(filepath.Join(path, name))
+	if os.IsNotExist(err) || err == nil && len(entries) > 0 {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", name, err)
+	}
+	err = os.Remove(filepath.Join(path, name))
+	if err != nil {
+		return fmt.Errorf("failed to remove directory %s: %w", name, err)
+	}
 	return nil
 }
+
+// prepareTarget creates the directory for the destination of a rename or
+// copy, which must not exist yet.
+func prepareTarget(file *godiffy.FileDiff, path string) (string, error) {
+	target := filepath.Join(path, file.NewPath)
+	if _, err := os.Lstat(target); err == nil {
+		return "", fmt.Errorf("file %s already exists", file.NewPath)
+	}
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return "", fmt.Errorf("failed to create directory %s: %w", filepath.Dir(target), err)
+	}
+	return target, nil
+}
+
+// sourcePath returns the path a renamed or copied file comes from.
+func sourcePath(file *godiffy.FileDiff) string {
+	if file.OldPath != "" {
+		return file.OldPath
+	}
+	return file.OldName
+}
@@ -194,6 +194,310 @@ func TestMergeToPath_EmptyDiff(t *testing.T) {
 	}
 }
 
+func TestMergeToPath_ModifyExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	oldText := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n"
+	newText := "one\n2\nthree\nfour\nfive\nsix\nseven\n8\n"
+	if err := os.WriteFile(filepath.Join(dir, "m.txt"), []byte(oldText), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := godiffy.Compute(oldText, newText, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.OldPath, file.NewPath = "m.txt", "m.txt"
+
+	if err := MergeToPath(&godiffy.Diff{Files: []*godiffy.FileDiff{file}}, dir); err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "m.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != newText {
+		t.Errorf("content = %q; want %q", got, newText)
+	}
+}
+
+func TestMergeToPathWithOptions_VerifyHashes(t *testing.T) {
+	oldText, newText := "a\nb\nc\n", "a\nB\nc\n"
+	newDiff := func() *godiffy.Diff {
+		file, err := godiffy.Compute(oldText, newText, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.OldPath, file.NewPath = "v.txt", "v.txt"
+		file.OldHash = godiffy.BlobID([]byte(oldText), godiffy.ObjectFormatSHA1).Abbrev(7)
+		file.NewHash = godiffy.BlobID([]byte(newText), godiffy.ObjectFormatSHA1).Abbrev(7)
+		return &godiffy.Diff{Files: []*godiffy.FileDiff{file}}
+	}
+	opts := &Options{VerifyHashes: true}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "v.txt"), []byte(oldText), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := MergeToPathWithOptions(newDiff(), dir, opts); err != nil {
+		t.Fatalf("expected matching hashes to succeed, got %v", err)
+	}
+
+	// The patched file no longer matches the preimage.
+	err := MergeToPathWithOptions(newDiff(), dir, opts)
+	if err == nil {
+		t.Fatal("expected preimage mismatch, got nil")
+	}
+	if !strings.Contains(err.Error(), "preimage mismatch for v.txt") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+
+	// A wrong postimage hash is caught before writing.
+	if err := os.WriteFile(filepath.Join(dir, "v.txt"), []byte(oldText), 0644); err != nil {
+		t.Fatal(err)
+	}
+	diff := newDiff()
+	diff.Files[0].NewHash = godiffy.BlobID([]byte("other\n"), godiffy.ObjectFormatSHA1)
+	err = MergeToPathWithOptions(diff, dir, opts)
+	if err == nil {
+		t.Fatal("expected postimage mismatch, got nil")
+	}
+	if !strings.Contains(err.Error(), "postimage mismatch for v.txt") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+	checkTree(t, dir, map[string]string{"v.txt": oldText})
+
+	// Nor is a file renamed when its postimage would not match.
+	diff = newDiff()
+	diff.Files[0].Status, diff.Files[0].NewPath = godiffy.FileStatusRenamed, "w.txt"
+	diff.Files[0].NewHash = godiffy.BlobID([]byte("other\n"), godiffy.ObjectFormatSHA1)
+	err = MergeToPathWithOptions(diff, dir, opts)
+	if err == nil || !strings.Contains(err.Error(), "postimage mismatch for w.txt") {
+		t.Errorf("expected postimage mismatch, got %v", err)
+	}
+	checkTree(t, dir, map[string]string{"v.txt": oldText})
+}
+
+// reverseTestDiff renames, deletes, creates and edits files and changes a
+// mode, as printed by "git diff -M".
+const reverseTestDiff = `diff --git a/del.txt b/del.txt
+deleted file mode 100644
+index 286c5f5..0000000
+--- a/del.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+diff --git a/mod.txt b/mod.txt
+index de98044..a7bc997 100644
+--- a/mod.txt
++++ b/mod.txt
+@@ -1,3 +1,4 @@
+ a
+-b
++B
+ c
++d
+diff --git a/ren.txt b/moved.txt
+similarity index 79%
+rename from ren.txt
+rename to moved.txt
+index f00c965..33011fd 100644
+--- a/ren.txt
++++ b/moved.txt
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+diff --git a/new.txt b/new.txt
+new file mode 100644
+index 0000000..92d5444
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++fresh
+diff --git a/run.sh b/run.sh
+old mode 100644
+new mode 100755
+`
+
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func checkTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(files) {
+		t.Errorf("expected %d files, got %d", len(files), len(entries))
+	}
+	for name, want := range files {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("failed to read %s: %v", name, err)
+			continue
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q; want %q", name, data, want)
+		}
+	}
+}
+
+func TestMergeToPathWithOptions_Reverse(t *testing.T) {
+	before := map[string]string{
+		"ren.txt": "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+		"del.txt": "gone\n",
+		"mod.txt": "a\nb\nc\n",
+		"run.sh":  "#!/bin/sh\necho hi\n",
+	}
+	after := map[string]string{
+		"moved.txt": "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n",
+		"new.txt":   "fresh\n",
+		"mod.txt":   "a\nB\nc\nd\n",
+		"run.sh":    "#!/bin/sh\necho hi\n",
+	}
+	diff, err := godiffy.Parse(reverseTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeTree(t, dir, before)
+
+	if err := MergeToPathWithOptions(diff, dir, &Options{VerifyHashes: true}); err != nil {
+		t.Fatalf("expected forward apply to succeed, got %v", err)
+	}
+	checkTree(t, dir, after)
+	info, err := os.Stat(filepath.Join(dir, "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perms := info.Mode().Perm(); perms != 0755 {
+		t.Errorf("perms = %v; want 0755", perms)
+	}
+
+	if err := MergeToPathWithOptions(diff, dir, &Options{VerifyHashes: true, Reverse: true}); err != nil {
+		t.Fatalf("expected reverse apply to succeed, got %v", err)
+	}
+	checkTree(t, dir, before)
+	info, err = os.Stat(filepath.Join(dir, "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perms := info.Mode().Perm(); perms != 0644 {
+		t.Errorf("perms = %v; want 0644", perms)
+	}
+}
+
+func TestMergeToPathWithOptions_ReverseCopy(t *testing.T) {
+	// From "git diff --cached -C --find-copies-harder".
+	diff, err := godiffy.Parse("diff --git a/a.txt b/b.txt\n" +
+		"similarity index 81%\n" +
+		"copy from a.txt\n" +
+		"copy to b.txt\n" +
+		"index f00c965..088bd5d 100644\n" +
+		"--- a/a.txt\n" +
+		"+++ b/b.txt\n" +
+		"@@ -7,4 +7,4 @@\n" +
+		" 7\n 8\n 9\n-10\n+ten\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := map[string]string{"a.txt": "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"}
+	dir := t.TempDir()
+	writeTree(t, dir, before)
+
+	if err := MergeToPathWithOptions(diff, dir, &Options{VerifyHashes: true}); err != nil {
+		t.Fatalf("expected forward apply to succeed, got %v", err)
+	}
+	checkTree(t, dir, map[string]string{"a.txt": before["a.txt"], "b.txt": "1\n2\n3\n4\n5\n6\n7\n8\n9\nten\n"})
+
+	if err := MergeToPathWithOptions(diff, dir, &Options{VerifyHashes: true, Reverse: true}); err != nil {
+		t.Fatalf("expected reverse apply to remove the copy, got %v", err)
+	}
+	checkTree(t, dir, before)
+}
+
+func TestMergeToPath_RenameTargetExists(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "x\n", "b.txt": "y\n"})
+	diff := godiffy.Diff{Files: []*godiffy.FileDiff{
+		{Status: godiffy.FileStatusRenamed, OldPath: "a.txt", NewPath: "b.txt"},
+	}}
+
+	err := MergeToPath(&diff, dir)
+	if err == nil {
+		t.Fatal("expected error for existing rename target, got nil")
+	}
+	if !strings.Contains(err.Error(), "failed to handle renamed file b.txt") {
+		t.Errorf("wrong wrapper: %v", err)
+	}
+}
+
+const submoduleTestDiff = `diff --git a/sub b/sub
+index 5796ffd..6131df2 160000
+--- a/sub
++++ b/sub
+@@ -1 +1 @@
+-Subproject commit 5796ffdde2d2e065f32b798ccac210b009f517b1
++Subproject commit 6131df29addf4b6586a4e7c0c6523ad32ae1cbba
+diff --git a/added b/added
+new file mode 160000
+index 0000000..ba4d687
+--- /dev/null
++++ b/added
+@@ -0,0 +1 @@
++Subproject commit ba4d6873e0d2f2e4a0c48e2f0e0e29e8a6a3a6f1
+diff --git a/gone b/gone
+deleted file mode 160000
+index 46841a5..0000000
+--- a/gone
++++ /dev/null
+@@ -1 +0,0 @@
+-Subproject commit 46841a5b1f3e9d0f0f1b7c3a1d5e6f7a8b9c0d1e
+`
+
+func TestMergeToPath_Submodules(t *testing.T) {
+	diff, err := godiffy.Parse(submoduleTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"sub", "gone"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTree(t, filepath.Join(dir, "sub"), map[string]string{"f": "checked out\n"})
+
+	if err := MergeToPathWithOptions(diff, dir, &Options{VerifyHashes: true}); err != nil {
+		t.Fatalf("expected submodules to apply, got %v", err)
+	}
+	checkTree(t, filepath.Join(dir, "sub"), map[string]string{"f": "checked out\n"})
+	if info, err := os.Stat(filepath.Join(dir, "added")); err != nil || !info.IsDir() {
+		t.Errorf("expected directory for new submodule, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "gone")); !os.IsNotExist(err) {
+		t.Errorf("expected empty submodule directory to be removed, got %v", err)
+	}
+
+	typeChange := godiffy.Diff{Files: []*godiffy.FileDiff{
+		{Status: godiffy.FileStatusModified, OldPath: "sub", NewPath: "sub", OldMode: "100644", NewMode: "160000"},
+	}}
+	if err := MergeToPath(&typeChange, dir); err == nil || !strings.Contains(err.Error(), "failed to handle submodule sub") {
+		t.Errorf("expected error for file turned into submodule, got %v", err)
+	}
+}
+
 //
 // Unit tests for each handler
 //
@@ -231,7 +535,7 @@ func TestHandleNewFile_SuccessAndInvalidMode(t *testing.T) {
 	}
 
 	// success
-	if err := handleNewFile(f, dir); err != nil {
+	if err := handleNewFile(f, dir, &Options{}); err != nil {
 		t.Fatalf("expected success, got %v", err)
 	}
 	out := filepath.Join(dir, f.NewPath)
@@ -250,7 +554,7 @@ func TestHandleNewFile_SuccessAndInvalidMode(t *testing.T) {
 		NewMode: "bad",
 		Hunks:   f.Hunks,
 	}
-	err := handleNewFile(f2, dir)
+	err := handleNewFile(f2, dir, &Options{})
 	if err == nil {
 		t.Fatal("expected mode parse error, got nil")
 	}
@@ -274,7 +578,7 @@ func TestHandleModifiedFile_SuccessAndInvalidMode(t *testing.T) {
 	}
 
 	// success
-	if err := handleModifiedFile(f, dir); err != nil {
+	if err := handleModifiedFile(f, dir, &Options{}); err != nil {
 		t.Fatalf("expected success, got %v", err)
 	}
 	out := filepath.Join(dir, f.NewPath)
@@ -289,7 +593,7 @@ func TestHandleModifiedFile_SuccessAndInvalidMode(t *testing.T) {
 
 	// invalid mode
 	f.NewMode = "oops"
-	err := handleModifiedFile(f, dir)
+	err := handleModifiedFile(f, dir, &Options{})
 	if err == nil {
 		t.Fatal("expected mode parse error, got nil")
 	}
@@ -0,0 +1,116 @@
+package gomergy
+
+import (
+	"strings"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+// lineMatcher returns how lines of a file are compared with lines of a
+// hunk. Ignoring whitespace ignores their endings as well.
+func (o *Options) lineMatcher() func(have, want string) bool {
+	if o.IgnoreWhitespace {
+		return fuzzyMatch
+	}
+	switch o.LineEndings {
+	case LineEndingIgnore, LineEndingLF, LineEndingCRLF:
+		return func(have, want string) bool {
+			return godiffy.TrimLineEnding(have) == godiffy.TrimLineEnding(want)
+		}
+	}
+	return func(have, want string) bool {
+		return have == want
+	}
+}
+
+// fuzzyMatch compares two lines like git apply --ignore-whitespace: a run
+// of whitespace in one matches any run in the other, but not none, so
+// indentation and trailing whitespace must be there on both sides. Line
+// endings are ignored.
+func fuzzyMatch(have, want string) bool {
+	a := strings.TrimRight(have, "\r\n")
+	b := strings.TrimRight(want, "\r\n")
+	for a != "" && b != "" {
+		if isSpace(a[0]) {
+			if !isSpace(b[0]) {
+				return false
+			}
+			a = strings.TrimLeftFunc(a, isSpaceRune)
+			b = strings.TrimLeftFunc(b, isSpaceRune)
+			continue
+		}
+		if a[0] != b[0] {
+			return false
+		}
+		a, b = a[1:], b[1:]
+	}
+	return a == "" && b == ""
+}
+
+// isSpace is git's isspace: space, tab and line ending characters.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isSpaceRune(r rune) bool {
+	return r < 0x80 && isSpace(byte(r))
+}
+
+// addedLine returns an added line as it is written into a file whose lines
+// mostly end in ending.
+func (o *Options) addedLine(line, ending string) string {
+	if o.LineEndings == LineEndingKeep || !strings.HasSuffix(line, "\n") {
+		return line
+	}
+	return godiffy.TrimLineEnding(line) + ending
+}
+
+// readEndings converts the content of a file before it is patched.
+func (o *Options) readEndings(content string) string {
+	switch o.LineEndings {
+	case LineEndingAutoCRLF, LineEndingAutoCRLFInput:
+		return strings.ReplaceAll(content, "\r\n", "\n")
+	}
+	return content
+}
+
+// writeEndings converts patched content before it is written to a file.
+func (o *Options) writeEndings(content string) string {
+	switch o.LineEndings {
+	case LineEndingLF, LineEndingAutoCRLFInput:
+		return setLineEndings(content, "\n")
+	case LineEndingCRLF, LineEndingAutoCRLF:
+		return setLineEndings(content, "\r\n")
+	}
+	return content
+}
+
+// fileLineEnding returns the ending most lines have, "\n" if there is a
+// tie.
+func fileLineEnding(lines []string) string {
+	crlf, lf := 0, 0
+	for _, line := range lines {
+		switch {
+		case strings.HasSuffix(line, "\r\n"):
+			crlf++
+		case strings.HasSuffix(line, "\n"):
+			lf++
+		}
+	}
+	if crlf > lf {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// setLineEndings gives every terminated line of content the same ending.
+func setLineEndings(content, ending string) string {
+	var b strings.Builder
+	for line := range strings.Lines(content) {
+		if strings.HasSuffix(line, "\n") {
+			line = godiffy.TrimLineEnding(line) + ending
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
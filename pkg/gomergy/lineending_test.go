@@ -0,0 +1,98 @@
+package gomergy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+func TestApplyHunksLineEndings(t *testing.T) {
+	// A patch made on LF content, applied to a file checked out with CRLF.
+	file, err := godiffy.Compute("a\nb\nc\n", "a\nB\nc\nd\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := "a\r\nb\r\nc\r\n"
+	tests := []struct {
+		mode      LineEndingMode
+		want      string
+		wantError bool
+	}{
+		{mode: LineEndingKeep, wantError: true},
+		{mode: LineEndingIgnore, want: "a\r\nB\r\nc\r\nd\r\n"},
+		{mode: LineEndingLF, want: "a\nB\nc\nd\n"},
+		{mode: LineEndingCRLF, want: "a\r\nB\r\nc\r\nd\r\n"},
+		{mode: LineEndingAutoCRLF, want: "a\r\nB\r\nc\r\nd\r\n"},
+		{mode: LineEndingAutoCRLFInput, want: "a\nB\nc\nd\n"},
+	}
+	for _, tt := range tests {
+		opts := &Options{LineEndings: tt.mode}
+		got, err := applyHunksWithOptions(opts.readEndings(target), file.Hunks, opts)
+		if tt.wantError {
+			if err == nil {
+				t.Errorf("mode %d: expected error, got %q", tt.mode, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("mode %d: expected success, got %v", tt.mode, err)
+			continue
+		}
+		if got = opts.writeEndings(got); got != tt.want {
+			t.Errorf("mode %d: content = %q; want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestApplyHunksIgnoreEndingsKeepsMixedFile(t *testing.T) {
+	file, err := godiffy.Compute("a\nb\nc", "a\nx\nb\nc\nd", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := applyHunksWithOptions("a\r\nb\nc", file.Hunks, &Options{LineEndings: LineEndingIgnore})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if want := "a\r\nx\nb\nc\nd"; got != want {
+		t.Errorf("content = %q; want %q", got, want)
+	}
+}
+
+func TestMergeToPathWithOptions_AutoCRLF(t *testing.T) {
+	oldText, newText := "one\ntwo\n", "one\n2\nthree\n"
+	file, err := godiffy.Compute(oldText, newText, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.OldPath, file.NewPath, file.NewMode = "f.txt", "f.txt", godiffy.FileModeRegular
+	file.OldHash = godiffy.BlobID([]byte(oldText), godiffy.ObjectFormatSHA1)
+	file.NewHash = godiffy.BlobID([]byte(newText), godiffy.ObjectFormatSHA1)
+	added := &godiffy.FileDiff{
+		Status: godiffy.FileStatusNew, OldPath: "new.txt", NewPath: "new.txt", NewMode: godiffy.FileModeRegular,
+		Hunks: []*godiffy.Hunk{{Lines: []*godiffy.HunkLine{{Type: godiffy.HunkLineAdded, Content: "x\n"}}}},
+	}
+	diff := &godiffy.Diff{Files: []*godiffy.FileDiff{file, added}}
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"f.txt": "one\r\ntwo\r\n"})
+	if err := MergeToPathWithOptions(diff, dir, &Options{VerifyHashes: true, LineEndings: LineEndingAutoCRLF}); err != nil {
+		t.Fatalf("expected apply to succeed, got %v", err)
+	}
+	checkTree(t, dir, map[string]string{"f.txt": "one\r\n2\r\nthree\r\n", "new.txt": "x\r\n"})
+
+	if err := MergeToPathWithOptions(diff, dir, &Options{Reverse: true}); err == nil {
+		t.Errorf("expected exact line endings not to match")
+	}
+	if err := MergeToPathWithOptions(diff, dir, &Options{Reverse: true, LineEndings: LineEndingIgnore}); err != nil {
+		t.Fatalf("expected reverse apply to succeed, got %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "f.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "one\r\ntwo\r\n" {
+		t.Errorf("f.txt = %q; want the CRLF original", data)
+	}
+}
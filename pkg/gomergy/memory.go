@@ -0,0 +1,59 @@
+package gomergy
+
+import (
+	"fmt"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+// Apply patches original, the content of file before the change, in memory
+// and returns the new content with the result of each hunk. opts works as
+// for MergeToPathWithOptions: VerifyHashes checks original and the result
+// against the index line, and with Reject a hunk that does not apply is left
+// out and marked in its HunkResult instead of failing the call. A nil opts
+// uses the zero Options.
+func Apply(original []byte, file *godiffy.FileDiff, opts *Options) ([]byte, []HunkResult, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if file.IsBinary {
+		return nil, nil, fmt.Errorf("cannot apply binary diff of %s", file.NewPath)
+	}
+	diff, err := prepareDiff(&godiffy.Diff{Files: []*godiffy.FileDiff{file}}, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	file = diff.Files[0]
+
+	// Link targets never get other line endings.
+	content := string(original)
+	if !file.IsSymlink() {
+		content = opts.readEndings(content)
+	}
+	if opts.VerifyHashes && file.Status != godiffy.FileStatusNew && file.OldHash != "" && !file.OldHash.IsNull() {
+		if err := checkBlob("preimage", file.NewPath, file.OldHash, []byte(content)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	result, results := applyMatchingHunks(content, file.Hunks, opts)
+	if err := hunkError(file.Hunks, results); err != nil {
+		if !opts.Reject {
+			return nil, results, fmt.Errorf("failed to apply hunks to %s: %w", file.NewPath, err)
+		}
+	} else if opts.VerifyHashes && file.Status != godiffy.FileStatusDeleted && file.NewHash != "" && !file.NewHash.IsNull() {
+		if err := checkBlob("postimage", file.NewPath, file.NewHash, []byte(result)); err != nil {
+			return nil, results, err
+		}
+	}
+	if !file.IsSymlink() {
+		result = opts.writeEndings(result)
+	}
+	return []byte(result), results, nil
+}
+
+// ApplyString is Apply for content held in a string.
+func ApplyString(original string, file *godiffy.FileDiff, opts *Options) (string, []HunkResult, error) {
+	result, results, err := Apply([]byte(original), file, opts)
+	return string(result), results, err
+}
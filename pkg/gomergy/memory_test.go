@@ -0,0 +1,119 @@
+package gomergy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+func TestApply(t *testing.T) {
+	diff, err := godiffy.Parse(rejectTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := diff.Files[0]
+	want := numberLines(map[int]string{3: "three", 17: "seventeen"})
+
+	got, results, err := Apply([]byte(numberLines(nil)), file, &Options{VerifyHashes: true})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("content = %q; want %q", got, want)
+	}
+	wantResults := []HunkResult{{Applied: true, Line: 1}, {Applied: true, Line: 14}}
+	if len(results) != 2 || results[0] != wantResults[0] || results[1] != wantResults[1] {
+		t.Errorf("results = %+v; want %+v", results, wantResults)
+	}
+
+	// Two lines inserted at the top move both hunks.
+	shifted, results, err := ApplyString("a\nb\n"+numberLines(nil), file, nil)
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if shifted != "a\nb\n"+want {
+		t.Errorf("content = %q", shifted)
+	}
+	if results[0].Line != 3 || results[0].Offset != 2 || results[1].Line != 16 || results[1].Offset != 2 {
+		t.Errorf("results = %+v; want both hunks 2 lines down", results)
+	}
+
+	undone, _, err := ApplyString(want, file, &Options{Reverse: true})
+	if err != nil || undone != numberLines(nil) {
+		t.Errorf("reverse = %q, %v; want the original back", undone, err)
+	}
+}
+
+func TestApply_Rejects(t *testing.T) {
+	diff, err := godiffy.Parse(rejectTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := diff.Files[0]
+	original := numberLines(map[int]string{2: "two"})
+
+	_, results, err := ApplyString(original, file, nil)
+	if err == nil || !strings.Contains(err.Error(), "hunk #1 does not apply") {
+		t.Fatalf("expected hunk #1 to fail, got %v", err)
+	}
+	if len(results) != 2 || results[0].Applied || !results[1].Applied {
+		t.Errorf("results = %+v; want only hunk #2 applied", results)
+	}
+
+	// The preimage check runs first and the content was changed.
+	_, _, err = ApplyString(original, file, &Options{Reject: true, VerifyHashes: true})
+	if err == nil || !strings.Contains(err.Error(), "preimage mismatch") {
+		t.Fatalf("expected preimage mismatch, got %v", err)
+	}
+	got, results, err := ApplyString(original, file, &Options{Reject: true})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if want := numberLines(map[int]string{2: "two", 17: "seventeen"}); got != want {
+		t.Errorf("content = %q; want %q", got, want)
+	}
+	if results[0].Applied || !results[1].Applied {
+		t.Errorf("results = %+v; want only hunk #2 applied", results)
+	}
+}
+
+func TestApply_NewAndDeletedFiles(t *testing.T) {
+	added, err := godiffy.Compute("", "x\ny\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added.Status = godiffy.FileStatusNew
+	got, _, err := ApplyString("", added, nil)
+	if err != nil || got != "x\ny\n" {
+		t.Errorf("new file = %q, %v; want %q", got, err, "x\ny\n")
+	}
+
+	deleted, err := godiffy.Compute("x\ny\n", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted.Status = godiffy.FileStatusDeleted
+	got, _, err = ApplyString("x\ny\n", deleted, nil)
+	if err != nil || got != "" {
+		t.Errorf("deleted file = %q, %v; want empty", got, err)
+	}
+}
+
+func TestApply_Binary(t *testing.T) {
+	file := &godiffy.FileDiff{NewPath: "a.bin", Status: godiffy.FileStatusModified, IsBinary: true}
+	if _, _, err := Apply([]byte{0}, file, nil); err == nil {
+		t.Fatal("expected error for binary diff, got nil")
+	}
+}
+
+func TestApply_LineEndings(t *testing.T) {
+	file, err := godiffy.Compute("a\nb\n", "a\nB\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := ApplyString("a\r\nb\r\n", file, &Options{LineEndings: LineEndingAutoCRLF})
+	if err != nil || got != "a\r\nB\r\n" {
+		t.Errorf("content = %q, %v; want %q", got, err, "a\r\nB\r\n")
+	}
+}
@@ -0,0 +1,413 @@
+package gomergy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+const defaultMarkerSize = 7
+
+// baseChange is a change of one side against the base: the base lines from
+// start up to end are replaced with lines.
+type baseChange struct {
+	start, end int
+	lines      []string
+}
+
+// Merge3 merges the changes ours and theirs made to base, like git
+// merge-file. Changes to different regions of base are combined; changes
+// that overlap or touch are a conflict unless both sides made the same
+// change. Conflicts are written with markers in opts.Style and listed in
+// the result. A nil opts uses the zero Merge3Options.
+func Merge3(base, ours, theirs string, opts *Merge3Options) (*MergeResult, error) {
+	if opts == nil {
+		opts = &Merge3Options{}
+	}
+	if opts.Style < ConflictStyleMerge || opts.Style > ConflictStyleZdiff3 {
+		return nil, fmt.Errorf("unknown conflict style: %d", opts.Style)
+	}
+	if opts.MarkerSize < 0 {
+		return nil, fmt.Errorf("invalid marker size: %d", opts.MarkerSize)
+	}
+	baseLines := splitLines(base)
+	oursChanges, err := baseChanges(base, ours, opts.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	theirsChanges, err := baseChanges(base, theirs, opts.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []*mergeRegion
+	pos := 0
+	for len(oursChanges) > 0 || len(theirsChanges) > 0 {
+		// A region starts at the first change and grows while a change of
+		// either side overlaps or touches it.
+		start, end := regionStart(oursChanges, theirsChanges), 0
+		var oursRegion, theirsRegion []baseChange
+		for grown := true; grown; {
+			grown = false
+			if len(oursChanges) > 0 && oursChanges[0].start <= max(end, start) {
+				end = max(end, oursChanges[0].end)
+				oursRegion, oursChanges = append(oursRegion, oursChanges[0]), oursChanges[1:]
+				grown = true
+			}
+			if len(theirsChanges) > 0 && theirsChanges[0].start <= max(end, start) {
+				end = max(end, theirsChanges[0].end)
+				theirsRegion, theirsChanges = append(theirsRegion, theirsChanges[0]), theirsChanges[1:]
+				grown = true
+			}
+		}
+
+		regions = append(regions, resolvedRegion(baseLines[pos:start]))
+		region := &mergeRegion{
+			baseStart: start,
+			baseEnd:   end,
+			ours:      applyBaseChanges(baseLines, start, end, oursRegion),
+			theirs:    applyBaseChanges(baseLines, start, end, theirsRegion),
+		}
+		switch {
+		case len(theirsRegion) == 0:
+			region = &mergeRegion{oursOnly: true, baseStart: start, baseEnd: end, ours: region.ours, theirs: region.ours}
+		case len(oursRegion) == 0:
+			region = &mergeRegion{theirsOnly: true, baseStart: start, baseEnd: end, ours: region.theirs, theirs: region.theirs}
+		case sameChange(oursRegion, theirsRegion):
+			region = resolvedRegion(region.ours)
+		default:
+			region.conflict = true
+		}
+		regions = append(regions, region)
+		pos = end
+	}
+	regions = append(regions, resolvedRegion(baseLines[pos:]))
+
+	switch opts.Style {
+	case ConflictStyleMerge:
+		regions, err = refineConflicts(regions, opts.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		regions = joinConflicts(regions)
+	case ConflictStyleZdiff3:
+		regions = trimConflicts(regions)
+	}
+
+	m := &merger{opts: opts}
+	oursLines, theirsLines := splitLines(ours), splitLines(theirs)
+	oursLine, theirsLine := 0, 0
+	for _, region := range regions {
+		if region.conflict {
+			ending := "\n"
+			if needsCR(baseLines, oursLines, theirsLines, oursLine, theirsLine) {
+				ending = "\r\n"
+			}
+			m.conflict(ending, &Conflict{
+				BaseStart:   region.baseStart + 1,
+				OursStart:   oursLine + 1,
+				TheirsStart: theirsLine + 1,
+				Base:        baseLines[region.baseStart:region.baseEnd],
+				Ours:        region.ours,
+				Theirs:      region.theirs,
+			})
+		} else {
+			m.write(region.ours...)
+		}
+		// The side that did not change still has the base lines.
+		switch {
+		case region.oursOnly:
+			oursLine += len(region.ours)
+			theirsLine += region.baseEnd - region.baseStart
+		case region.theirsOnly:
+			oursLine += region.baseEnd - region.baseStart
+			theirsLine += len(region.theirs)
+		default:
+			oursLine += len(region.ours)
+			theirsLine += len(region.theirs)
+		}
+	}
+	return &MergeResult{Content: m.b.String(), Conflicts: m.conflicts}, nil
+}
+
+// mergeRegion is a stretch of the merged text: lines both sides agree on,
+// or a conflict between the lines of ours and theirs that replace the base
+// lines from baseStart up to baseEnd. oursOnly and theirsOnly mark agreed
+// lines that come from a change of only that side; lines both sides changed
+// alike are treated like those of the base, as git does.
+type mergeRegion struct {
+	conflict     bool
+	oursOnly     bool
+	theirsOnly   bool
+	baseStart    int
+	baseEnd      int
+	ours, theirs []string
+}
+
+func resolvedRegion(lines []string) *mergeRegion {
+	return &mergeRegion{ours: lines, theirs: lines}
+}
+
+// refineConflicts splits conflicts where ours and theirs have lines in
+// common, like git's zealous merge level.
+func refineConflicts(regions []*mergeRegion, algorithm godiffy.Algorithm) ([]*mergeRegion, error) {
+	var refined []*mergeRegion
+	for _, region := range regions {
+		if !region.conflict {
+			refined = append(refined, region)
+			continue
+		}
+		changes, err := baseChanges(strings.Join(region.ours, ""), strings.Join(region.theirs, ""), algorithm)
+		if err != nil {
+			return nil, err
+		}
+		pos, offset := 0, 0
+		for _, change := range changes {
+			refined = append(refined, resolvedRegion(region.ours[pos:change.start]))
+			refined = append(refined, &mergeRegion{
+				conflict:  true,
+				baseStart: region.baseStart,
+				baseEnd:   region.baseEnd,
+				ours:      region.ours[change.start:change.end],
+				theirs:    region.theirs[change.start+offset : change.start+offset+len(change.lines)],
+			})
+			offset += len(change.lines) - (change.end - change.start)
+			pos = change.end
+		}
+		refined = append(refined, resolvedRegion(region.ours[pos:]))
+	}
+	return refined, nil
+}
+
+// trimConflicts moves the lines both sides of a conflict start or end with
+// out of it, like git's zdiff3 style.
+func trimConflicts(regions []*mergeRegion) []*mergeRegion {
+	var trimmed []*mergeRegion
+	for _, region := range regions {
+		if !region.conflict {
+			trimmed = append(trimmed, region)
+			continue
+		}
+		prefix := commonPrefix(region.ours, region.theirs)
+		suffix := commonSuffix(region.ours[prefix:], region.theirs[prefix:])
+		conflict := *region
+		conflict.ours = region.ours[prefix : len(region.ours)-suffix]
+		conflict.theirs = region.theirs[prefix : len(region.theirs)-suffix]
+		trimmed = append(trimmed,
+			resolvedRegion(region.ours[:prefix]),
+			&conflict,
+			resolvedRegion(region.ours[len(region.ours)-suffix:]))
+	}
+	return trimmed
+}
+
+// joinConflicts turns conflicts that are at most 3 lines apart, or apart
+// only by lines without letters or digits, into one, as git does because
+// that takes up no more lines. A change of one side between them keeps
+// them apart.
+func joinConflicts(regions []*mergeRegion) []*mergeRegion {
+	var joined []*mergeRegion
+	var last *mergeRegion // the last conflict, if no one-sided change follows it
+	var gap []string
+	for _, region := range regions {
+		if region.oursOnly || region.theirsOnly {
+			if len(gap) > 0 {
+				joined = append(joined, resolvedRegion(gap))
+			}
+			joined = append(joined, region)
+			last, gap = nil, nil
+			continue
+		}
+		if !region.conflict {
+			gap = append(gap, region.ours...)
+			continue
+		}
+		if last != nil && (len(gap) <= 3 || !hasAlnum(gap)) {
+			last.ours = slices.Concat(last.ours, gap, region.ours)
+			last.theirs = slices.Concat(last.theirs, gap, region.theirs)
+			last.baseStart = min(last.baseStart, region.baseStart)
+			last.baseEnd = max(last.baseEnd, region.baseEnd)
+			gap = nil
+			continue
+		}
+		if len(gap) > 0 {
+			joined = append(joined, resolvedRegion(gap))
+		}
+		last = &mergeRegion{}
+		*last = *region
+		joined = append(joined, last)
+		gap = nil
+	}
+	if len(gap) > 0 {
+		joined = append(joined, resolvedRegion(gap))
+	}
+	return joined
+}
+
+func hasAlnum(lines []string) bool {
+	for _, line := range lines {
+		if strings.IndexFunc(line, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// baseChanges diffs side against base and returns its changes in order.
+func baseChanges(base, side string, algorithm godiffy.Algorithm) ([]baseChange, error) {
+	file, err := godiffy.Compute(base, side, &godiffy.DiffOptions{Algorithm: algorithm})
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff against base: %w", err)
+	}
+	changes := make([]baseChange, 0, len(file.Hunks))
+	for _, hunk := range file.Hunks {
+		start := hunkIndex(hunk)
+		change := baseChange{start: start, end: start + hunk.OldLineCount}
+		for _, line := range hunk.Lines {
+			if line.Type == godiffy.HunkLineAdded {
+				change.lines = append(change.lines, line.Content)
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// sameChange reports whether both sides made one change, the same, to the
+// same base lines. Like git, ours and theirs that only end up alike
+// through different changes are a conflict, which refineConflicts then
+// resolves where markers do not show base.
+func sameChange(ours, theirs []baseChange) bool {
+	return len(ours) == 1 && len(theirs) == 1 &&
+		ours[0].start == theirs[0].start && ours[0].end == theirs[0].end &&
+		slices.Equal(ours[0].lines, theirs[0].lines)
+}
+
+// regionStart returns where the first of the remaining changes starts.
+func regionStart(ours, theirs []baseChange) int {
+	switch {
+	case len(ours) == 0:
+		return theirs[0].start
+	case len(theirs) == 0:
+		return ours[0].start
+	}
+	return min(ours[0].start, theirs[0].start)
+}
+
+// applyBaseChanges returns the base lines from start up to end as changes,
+// which lie within them, leave them.
+func applyBaseChanges(base []string, start, end int, changes []baseChange) []string {
+	var lines []string
+	pos := start
+	for _, change := range changes {
+		lines = append(lines, base[pos:change.start]...)
+		lines = append(lines, change.lines...)
+		pos = change.end
+	}
+	return append(lines, base[pos:end]...)
+}
+
+// merger writes the merged text and keeps track of its conflicts.
+type merger struct {
+	opts      *Merge3Options
+	b         strings.Builder
+	line      int // lines written so far
+	conflicts []*Conflict
+}
+
+func (m *merger) write(lines ...string) {
+	for _, line := range lines {
+		m.b.WriteString(line)
+	}
+	m.line += len(lines)
+}
+
+// conflict writes c with markers whose lines end in ending.
+func (m *merger) conflict(ending string, c *Conflict) {
+	c.Line = m.line + 1
+	m.conflicts = append(m.conflicts, c)
+	m.marker('<', m.opts.OursLabel, ending)
+	m.section(c.Ours, ending)
+	if m.opts.Style != ConflictStyleMerge {
+		m.marker('|', m.opts.BaseLabel, ending)
+		m.section(c.Base, ending)
+	}
+	m.marker('=', "", ending)
+	m.section(c.Theirs, ending)
+	m.marker('>', m.opts.TheirsLabel, ending)
+}
+
+// marker writes a conflict marker line.
+func (m *merger) marker(c byte, label, ending string) {
+	size := m.opts.MarkerSize
+	if size == 0 {
+		size = defaultMarkerSize
+	}
+	line := strings.Repeat(string(c), size)
+	if label != "" {
+		line += " " + label
+	}
+	m.write(line + ending)
+}
+
+// section writes the lines of one side of a conflict, ending the last one
+// so that the next marker starts a line.
+func (m *merger) section(lines []string, ending string) {
+	m.write(lines...)
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		m.b.WriteString(ending)
+	}
+}
+
+// needsCR reports whether the markers of a conflict at the 0-based lines
+// oursLine and theirsLine end in CRLF. Like git, it asks the lines before
+// the conflict on both sides, or the first ones, and then the first line
+// of base, and takes LF unless all that use CRLF.
+func needsCR(base, ours, theirs []string, oursLine, theirsLine int) bool {
+	crlf := lineIsCRLF(ours, max(oursLine-1, 0))
+	if crlf != 0 {
+		crlf = lineIsCRLF(theirs, max(theirsLine-1, 0))
+	}
+	if crlf != 0 {
+		crlf = lineIsCRLF(base, 0)
+	}
+	return crlf > 0
+}
+
+// lineIsCRLF returns 1 if line i of lines ends in CRLF, 0 if it does not
+// and -1 if that cannot be told. A last line without newline goes by the
+// one before it.
+func lineIsCRLF(lines []string, i int) int {
+	if len(lines) == 0 {
+		return -1
+	}
+	if !strings.HasSuffix(lines[i], "\n") {
+		if i == 0 {
+			return -1
+		}
+		i--
+	}
+	if strings.HasSuffix(lines[i], "\r\n") {
+		return 1
+	}
+	return 0
+}
+
+func commonPrefix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func commonSuffix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,201 @@
+package gomergy
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const (
+	merge3Base   = "a\nb\nc\nd\ne\nf\ng\n"
+	merge3Ours   = "a\nB\nc\nd\nE1\nx\nf\ng\n"
+	merge3Theirs = "a\nb\nc\nd\nE2\nx\nf\nG\n"
+)
+
+// Expected output from git merge-file -p -L ours -L base -L theirs.
+func TestMerge3_Styles(t *testing.T) {
+	tests := []struct {
+		style ConflictStyle
+		want  string
+	}{
+		{ConflictStyleMerge, "a\nB\nc\nd\n<<<<<<< ours\nE1\n=======\nE2\n>>>>>>> theirs\nx\nf\nG\n"},
+		{ConflictStyleDiff3, "a\nB\nc\nd\n<<<<<<< ours\nE1\nx\n||||||| base\ne\n=======\nE2\nx\n>>>>>>> theirs\nf\nG\n"},
+		{ConflictStyleZdiff3, "a\nB\nc\nd\n<<<<<<< ours\nE1\n||||||| base\ne\n=======\nE2\n>>>>>>> theirs\nx\nf\nG\n"},
+	}
+	for _, tt := range tests {
+		opts := &Merge3Options{Style: tt.style, OursLabel: "ours", BaseLabel: "base", TheirsLabel: "theirs"}
+		result, err := Merge3(merge3Base, merge3Ours, merge3Theirs, opts)
+		if err != nil {
+			t.Fatalf("style %d: expected success, got %v", tt.style, err)
+		}
+		if result.Content != tt.want {
+			t.Errorf("style %d: content = %q; want %q", tt.style, result.Content, tt.want)
+		}
+		if len(result.Conflicts) != 1 {
+			t.Fatalf("style %d: got %d conflicts, want 1", tt.style, len(result.Conflicts))
+		}
+		c := result.Conflicts[0]
+		if c.Line != 5 || c.BaseStart != 5 || c.OursStart != 5 || c.TheirsStart != 5 || !slices.Equal(c.Base, []string{"e\n"}) {
+			t.Errorf("style %d: unexpected conflict %+v", tt.style, c)
+		}
+	}
+}
+
+func TestMerge3_Conflict(t *testing.T) {
+	result, err := Merge3(merge3Base, merge3Ours, merge3Theirs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := result.Conflicts[0]
+	if !slices.Equal(c.Ours, []string{"E1\n"}) || !slices.Equal(c.Theirs, []string{"E2\n"}) {
+		t.Errorf("ours = %q, theirs = %q", c.Ours, c.Theirs)
+	}
+	if want := "a\nB\nc\nd\n<<<<<<<\nE1\n=======\nE2\n>>>>>>>\nx\nf\nG\n"; result.Content != want {
+		t.Errorf("content = %q; want %q", result.Content, want)
+	}
+
+	result, err = Merge3(merge3Base, merge3Ours, merge3Theirs, &Merge3Options{Style: ConflictStyleDiff3, MarkerSize: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = result.Conflicts[0]
+	if !slices.Equal(c.Ours, []string{"E1\n", "x\n"}) || !slices.Equal(c.Theirs, []string{"E2\n", "x\n"}) {
+		t.Errorf("ours = %q, theirs = %q", c.Ours, c.Theirs)
+	}
+	if want := "a\nB\nc\nd\n<<<\nE1\nx\n|||\ne\n===\nE2\nx\n>>>\nf\nG\n"; result.Content != want {
+		t.Errorf("content = %q; want %q", result.Content, want)
+	}
+}
+
+func TestMerge3_ConflictStarts(t *testing.T) {
+	// Only theirs inserts lines before the conflict, so ours is behind.
+	base := "1\n2\n3\n4\n5\n6\n7\n8\n"
+	ours := "1\n2\n3\n4\n5\n6\nO\n8\n"
+	theirs := "1\nT1\nT2\nT3\n2\n3\n4\n5\n6\nT\n8\n"
+	for _, style := range []ConflictStyle{ConflictStyleMerge, ConflictStyleDiff3, ConflictStyleZdiff3} {
+		result, err := Merge3(base, ours, theirs, &Merge3Options{Style: style})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Conflicts) != 1 {
+			t.Fatalf("style %d: got %d conflicts, want 1", style, len(result.Conflicts))
+		}
+		c := result.Conflicts[0]
+		if c.BaseStart != 7 || c.OursStart != 7 || c.TheirsStart != 10 || c.Line != 10 {
+			t.Errorf("style %d: conflict at base %d, ours %d, theirs %d, line %d; want 7, 7, 10, 10",
+				style, c.BaseStart, c.OursStart, c.TheirsStart, c.Line)
+		}
+	}
+}
+
+func TestMerge3_CRLFMarkers(t *testing.T) {
+	opts := &Merge3Options{OursLabel: "o", TheirsLabel: "t"}
+	result, err := Merge3("a\r\nb\r\nc\r\n", "a\r\nX\r\nc\r\n", "a\r\nY\r\nc\r\n", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a\r\n<<<<<<< o\r\nX\r\n=======\r\nY\r\n>>>>>>> t\r\nc\r\n"; result.Content != want {
+		t.Errorf("content = %q; want %q", result.Content, want)
+	}
+
+	// A side with LF lines keeps the markers LF.
+	result, err = Merge3("a\r\nb\r\nc\r\n", "a\nX\nc\n", "a\r\nY\r\nc\r\n", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<<<<<<< o\na\nX\nc\n=======\na\r\nY\r\nc\r\n>>>>>>> t\n"; result.Content != want {
+		t.Errorf("content = %q; want %q", result.Content, want)
+	}
+}
+
+func TestMerge3_Clean(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+	}{
+		{"separate changes", "a\nb\nc\nd\ne\n", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n"},
+		{"same change", "a\nb\nc\n", "a\nB\nc\n", "a\nB\nc\n", "a\nB\nc\n"},
+		{"one side", "a\nb\n", "a\nb\n", "a\nb\nc\n", "a\nb\nc\n"},
+		{"empty base", "", "x\n", "x\n", "x\n"},
+		{"no newline", "a\nb", "a\nb", "A\nb", "A\nb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Merge3(tt.base, tt.ours, tt.theirs, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Content != tt.want || len(result.Conflicts) != 0 {
+				t.Errorf("content = %q with %d conflicts; want %q", result.Content, len(result.Conflicts), tt.want)
+			}
+		})
+	}
+}
+
+// Checked against git merge-file: conflicts a few lines apart become one,
+// while a missing newline is added before the next marker.
+func TestMerge3_JoinsCloseConflicts(t *testing.T) {
+	result, err := Merge3("a\nb\nc\nd\n", "A1\nb\nc\nD1", "A2\nb\nc\nD2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<<<<<<<\nA1\nb\nc\nD1\n=======\nA2\nb\nc\nD2\n>>>>>>>\n"; result.Content != want {
+		t.Errorf("content = %q; want %q", result.Content, want)
+	}
+	if len(result.Conflicts) != 1 {
+		t.Errorf("got %d conflicts, want 1", len(result.Conflicts))
+	}
+}
+
+// The corpus holds base, ours and theirs with what "git merge-file -p -L
+// ours -L base -L theirs [--diff3|--zdiff3]" printed for them.
+func TestMerge3MatchesGitCorpus(t *testing.T) {
+	styles := map[string]ConflictStyle{
+		"merge":  ConflictStyleMerge,
+		"diff3":  ConflictStyleDiff3,
+		"zdiff3": ConflictStyleZdiff3,
+	}
+
+	dirs, err := filepath.Glob(filepath.Join("testdata", "merge3", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) == 0 {
+		t.Fatal("expected corpus directories, got none")
+	}
+	for _, dir := range dirs {
+		var texts [3]string
+		for i, name := range []string{"base", "ours", "theirs"} {
+			data, err := os.ReadFile(filepath.Join(dir, name+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			texts[i] = string(data)
+		}
+		for name, style := range styles {
+			want, err := os.ReadFile(filepath.Join(dir, name+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := &Merge3Options{Style: style, OursLabel: "ours", BaseLabel: "base", TheirsLabel: "theirs"}
+			result, err := Merge3(texts[0], texts[1], texts[2], opts)
+			if err != nil {
+				t.Fatalf("%s/%s: expected success, got %v", filepath.Base(dir), name, err)
+			}
+			if result.Content != string(want) {
+				t.Errorf("%s/%s: content =\n%s\nwant\n%s", filepath.Base(dir), name, result.Content, want)
+			}
+		}
+	}
+}
+
+func TestMerge3_InvalidOptions(t *testing.T) {
+	if _, err := Merge3("", "", "", &Merge3Options{Style: ConflictStyle(9)}); err == nil {
+		t.Error("expected error for unknown style, got nil")
+	}
+	if _, err := Merge3("", "", "", &Merge3Options{MarkerSize: -1}); err == nil {
+		t.Error("expected error for negative marker size, got nil")
+	}
+}
@@ -0,0 +1,68 @@
+package gomergy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+// Error names the hunks of the file that were saved to its reject file.
+func (r *FileReject) Error() string {
+	numbers := make([]string, len(r.Rejected))
+	for i, n := range r.Rejected {
+		numbers[i] = fmt.Sprintf("#%d", n)
+	}
+	return fmt.Sprintf("%s: rejected hunk %s, applied %d, saved to %s",
+		r.Path, strings.Join(numbers, ", "), r.Applied, r.RejectPath)
+}
+
+// Error sums up the rejected hunks, one line per file.
+func (e *RejectError) Error() string {
+	rejected := 0
+	lines := make([]string, len(e.Files))
+	for i, file := range e.Files {
+		rejected += len(file.Rejected)
+		lines[i] = file.Error()
+	}
+	return fmt.Sprintf("%d hunks rejected in %d files:\n%s", rejected, len(e.Files), strings.Join(lines, "\n"))
+}
+
+// writeReject saves the hunks of file at the indexes in failed next to it,
+// in the format of git apply --reject, and returns the FileReject
+// describing them.
+func writeReject(file *godiffy.FileDiff, failed []int, path string) error {
+	oldPath := file.NewPath
+	if file.Status == godiffy.FileStatusRenamed || file.Status == godiffy.FileStatusCopied {
+		oldPath = sourcePath(file)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff a/%s b/%s\t(rejected hunks)\n", oldPath, file.NewPath)
+	reject := &FileReject{
+		Path:       file.NewPath,
+		RejectPath: file.NewPath + ".rej",
+		Applied:    len(file.Hunks) - len(failed),
+	}
+	for _, i := range failed {
+		b.WriteString(file.Hunks[i].String())
+		reject.Rejected = append(reject.Rejected, i+1)
+		reject.Hunks = append(reject.Hunks, file.Hunks[i])
+	}
+	// A link planted at the reject path is replaced, not followed.
+	if err := checkPath(reject.RejectPath, path); err != nil {
+		return err
+	}
+	full := filepath.Join(path, reject.RejectPath)
+	if isSymlink(full) {
+		if err := os.Remove(full); err != nil {
+			return fmt.Errorf("failed to remove symlink %s: %w", reject.RejectPath, err)
+		}
+	}
+	err := os.WriteFile(full, []byte(b.String()), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write reject file %s: %w", reject.RejectPath, err)
+	}
+	return reject
+}
@@ -0,0 +1,140 @@
+package gomergy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+// From "git diff"; the first hunk no longer applies once line 2 changes.
+const rejectTestDiff = "diff --git a/a.txt b/a.txt\n" +
+	"index 0ff3bbb..7399f4e 100644\n" +
+	"--- a/a.txt\n" +
+	"+++ b/a.txt\n" +
+	"@@ -1,6 +1,6 @@\n" +
+	" 1\n 2\n-3\n+three\n 4\n 5\n 6\n" +
+	"@@ -14,7 +14,7 @@\n" +
+	" 14\n 15\n 16\n-17\n+seventeen\n 18\n 19\n 20\n" +
+	"diff --git a/b.txt b/b.txt\n" +
+	"index b77b4eb..7061c57 100644\n" +
+	"--- a/b.txt\n" +
+	"+++ b/b.txt\n" +
+	"@@ -1,2 +1,2 @@\n" +
+	" x\n-y\n+Y\n"
+
+// numberLines returns the lines 1 to 20, with some replaced.
+func numberLines(replaced map[int]string) string {
+	content := ""
+	for i := 1; i <= 20; i++ {
+		line, ok := replaced[i]
+		if !ok {
+			line = strconv.Itoa(i)
+		}
+		content += line + "\n"
+	}
+	return content
+}
+
+func TestMergeToPathWithOptions_Reject(t *testing.T) {
+	diff, err := godiffy.Parse(rejectTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.txt": numberLines(map[int]string{2: "two"}),
+		"b.txt": "x\ny\n",
+	})
+
+	err = MergeToPathWithOptions(diff, dir, &Options{Reject: true})
+	var rejectErr *RejectError
+	if !errors.As(err, &rejectErr) {
+		t.Fatalf("expected RejectError, got %v", err)
+	}
+	if len(rejectErr.Files) != 1 {
+		t.Fatalf("got %d rejected files, want 1", len(rejectErr.Files))
+	}
+	reject := rejectErr.Files[0]
+	if reject.Path != "a.txt" || reject.RejectPath != "a.txt.rej" || reject.Applied != 1 ||
+		len(reject.Rejected) != 1 || reject.Rejected[0] != 1 || reject.Hunks[0] != diff.Files[0].Hunks[0] {
+		t.Errorf("unexpected reject: %+v", reject)
+	}
+	if want := "1 hunks rejected in 1 files:\na.txt: rejected hunk #1, applied 1, saved to a.txt.rej"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	// As written by git apply --reject.
+	checkTree(t, dir, map[string]string{
+		"a.txt": numberLines(map[int]string{2: "two", 17: "seventeen"}),
+		"b.txt": "x\nY\n",
+		"a.txt.rej": "diff a/a.txt b/a.txt\t(rejected hunks)\n" +
+			"@@ -1,6 +1,6 @@\n" +
+			" 1\n 2\n-3\n+three\n 4\n 5\n 6\n",
+	})
+}
+
+func TestMergeToPathWithOptions_RejectReplacesLink(t *testing.T) {
+	diff, err := godiffy.Parse(rejectTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "root")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, dir, map[string]string{"a.txt": numberLines(map[int]string{2: "two"}), "b.txt": "x\ny\n"})
+	if err := os.Symlink("../outside", filepath.Join(dir, "a.txt.rej")); err != nil {
+		t.Fatal(err)
+	}
+
+	err = MergeToPathWithOptions(diff, dir, &Options{Reject: true})
+	var rejectErr *RejectError
+	if !errors.As(err, &rejectErr) {
+		t.Fatalf("expected RejectError, got %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(parent, "outside")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing written outside of the tree, got %v", err)
+	}
+	if isSymlink(filepath.Join(dir, "a.txt.rej")) {
+		t.Error("expected a.txt.rej to be a file, got a symlink")
+	}
+}
+
+func TestMergeToPathWithOptions_RejectAllApplied(t *testing.T) {
+	diff, err := godiffy.Parse(rejectTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": numberLines(nil), "b.txt": "x\ny\n"})
+
+	err = MergeToPathWithOptions(diff, dir, &Options{Reject: true, VerifyHashes: true})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt.rej")); !os.IsNotExist(err) {
+		t.Errorf("expected no reject file, got %v", err)
+	}
+}
+
+func TestMergeToPathWithOptions_WithoutReject(t *testing.T) {
+	diff, err := godiffy.Parse(rejectTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	original := numberLines(map[int]string{2: "two"})
+	writeTree(t, dir, map[string]string{"a.txt": original, "b.txt": "x\ny\n"})
+
+	err = MergeToPath(diff, dir)
+	var rejectErr *RejectError
+	if err == nil || errors.As(err, &rejectErr) {
+		t.Fatalf("expected a plain error, got %v", err)
+	}
+	checkTree(t, dir, map[string]string{"a.txt": original, "b.txt": "x\ny\n"})
+}
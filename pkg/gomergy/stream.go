@@ -0,0 +1,150 @@
+package gomergy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+// ApplyStream is Apply for content too large to hold in memory: it reads
+// the content before the change from src and writes the patched content to
+// dst as it goes. Only the lines around the hunk being applied are kept, so
+// a hunk is looked for no further than its own length from the line it
+// names, shifted by the offset of the hunk before it. Lines are given the
+// ending of the lines a hunk matched, and VerifyHashes is not supported,
+// since blob IDs need the size of the whole content. On error dst holds
+// what was written so far.
+func ApplyStream(dst io.Writer, src io.Reader, file *godiffy.FileDiff, opts *Options) (results []HunkResult, err error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.VerifyHashes {
+		return nil, errors.New("cannot verify hashes while streaming")
+	}
+	if file.IsBinary {
+		return nil, fmt.Errorf("cannot apply binary diff of %s", file.NewPath)
+	}
+	diff, err := prepareDiff(&godiffy.Diff{Files: []*godiffy.FileDiff{file}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	file = diff.Files[0]
+
+	// Link targets never get other line endings.
+	streamOpts := opts
+	if file.IsSymlink() {
+		streamOpts = &Options{}
+	}
+	s := &lineStream{src: bufio.NewReader(src), dst: bufio.NewWriter(dst), opts: streamOpts}
+	defer func() {
+		// A failed write leaves its error in s.dst, which is already in err.
+		if flushErr := s.dst.Flush(); flushErr != nil && !errors.Is(err, flushErr) {
+			err = errors.Join(err, fmt.Errorf("failed to write patched content: %w", flushErr))
+		}
+	}()
+	equal := opts.lineMatcher()
+	results = make([]HunkResult, len(file.Hunks))
+	offset := 0
+	for i, hunk := range file.Hunks {
+		preimage := hunkPreimage(hunk)
+		base := hunkIndex(hunk)
+		expected := max(base+offset, s.pos)
+		window := len(preimage)
+
+		// Lines the search cannot reach are written as they are.
+		if err := s.skipTo(expected - window); err != nil {
+			return results, err
+		}
+		if err := s.fill(expected + window + len(preimage) - s.pos); err != nil {
+			return results, err
+		}
+		at, ok := 0, false
+		for distance := 0; distance <= window && !ok; distance++ {
+			for _, candidate := range []int{expected - distance, expected + distance} {
+				first := candidate - s.pos
+				if first >= 0 && first+len(preimage) <= len(s.lines) && matchLines(s.lines[first:], preimage, equal) {
+					at, ok = candidate, true
+					break
+				}
+			}
+		}
+		if !ok {
+			if !opts.Reject {
+				return results, fmt.Errorf("failed to apply hunks to %s: hunk #%d does not apply at line %d", file.NewPath, i+1, hunk.OldStart)
+			}
+			continue
+		}
+		results[i] = HunkResult{Applied: true, Line: at + 1, Offset: at - base}
+		if err := s.skipTo(at); err != nil {
+			return results, err
+		}
+		matched := s.lines[:len(preimage)]
+		var b strings.Builder
+		writeHunk(&b, matched, hunk, fileLineEnding(matched), opts)
+		if err := s.write(b.String()); err != nil {
+			return results, err
+		}
+		s.lines = s.lines[len(preimage):]
+		s.pos += len(preimage)
+		offset = at - base
+	}
+	return results, s.skipTo(math.MaxInt)
+}
+
+// lineStream holds the lines read from src that are not written yet. pos
+// is the 0-based index of the first of them in the content.
+type lineStream struct {
+	src   *bufio.Reader
+	dst   *bufio.Writer
+	opts  *Options
+	lines []string
+	pos   int
+	eof   bool
+}
+
+// fill reads until n lines are held or src ends.
+func (s *lineStream) fill(n int) error {
+	for len(s.lines) < n && !s.eof {
+		line, err := s.src.ReadString('\n')
+		if err == io.EOF {
+			s.eof = true
+		} else if err != nil {
+			return fmt.Errorf("failed to read content: %w", err)
+		}
+		if line != "" {
+			s.lines = append(s.lines, s.opts.readEndings(line))
+		}
+	}
+	return nil
+}
+
+// skipTo writes the lines before index to as they are, or all that are
+// left if src ends first.
+func (s *lineStream) skipTo(to int) error {
+	for s.pos < to {
+		if err := s.fill(1); err != nil {
+			return err
+		}
+		if len(s.lines) == 0 {
+			return nil
+		}
+		if err := s.write(s.lines[0]); err != nil {
+			return err
+		}
+		s.lines = s.lines[1:]
+		s.pos++
+	}
+	return nil
+}
+
+func (s *lineStream) write(text string) error {
+	if _, err := s.dst.WriteString(s.opts.writeEndings(text)); err != nil {
+		return fmt.Errorf("failed to write patched content: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package gomergy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+func TestApplyStream(t *testing.T) {
+	diff, err := godiffy.Parse(rejectTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := diff.Files[0]
+	tests := []struct {
+		name     string
+		original string
+		opts     *Options
+		want     string
+		results  []HunkResult
+	}{
+		{
+			name:     "exact position",
+			original: numberLines(nil),
+			want:     numberLines(map[int]string{3: "three", 17: "seventeen"}),
+			results:  []HunkResult{{Applied: true, Line: 1}, {Applied: true, Line: 14}},
+		},
+		{
+			name:     "shifted",
+			original: "a\nb\n" + numberLines(nil),
+			want:     "a\nb\n" + numberLines(map[int]string{3: "three", 17: "seventeen"}),
+			results:  []HunkResult{{Applied: true, Line: 3, Offset: 2}, {Applied: true, Line: 16, Offset: 2}},
+		},
+		{
+			name:     "reject",
+			original: numberLines(map[int]string{2: "two"}),
+			opts:     &Options{Reject: true},
+			want:     numberLines(map[int]string{2: "two", 17: "seventeen"}),
+			results:  []HunkResult{{}, {Applied: true, Line: 14}},
+		},
+		{
+			name:     "reverse",
+			original: numberLines(map[int]string{3: "three", 17: "seventeen"}),
+			opts:     &Options{Reverse: true},
+			want:     numberLines(nil),
+			results:  []HunkResult{{Applied: true, Line: 1}, {Applied: true, Line: 14}},
+		},
+		{
+			name:     "crlf",
+			original: strings.ReplaceAll(numberLines(nil), "\n", "\r\n"),
+			opts:     &Options{LineEndings: LineEndingIgnore},
+			want:     strings.ReplaceAll(numberLines(map[int]string{3: "three", 17: "seventeen"}), "\n", "\r\n"),
+			results:  []HunkResult{{Applied: true, Line: 1}, {Applied: true, Line: 14}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			results, err := ApplyStream(&out, iotest.OneByteReader(strings.NewReader(tt.original)), file, tt.opts)
+			if err != nil {
+				t.Fatalf("expected success, got %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("content = %q; want %q", out.String(), tt.want)
+			}
+			if len(results) != len(tt.results) {
+				t.Fatalf("results = %+v; want %+v", results, tt.results)
+			}
+			for i := range results {
+				if results[i] != tt.results[i] {
+					t.Errorf("result %d = %+v; want %+v", i, results[i], tt.results[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyStream_MatchesApply(t *testing.T) {
+	for _, tc := range []struct{ old, new, target string }{
+		{"a\nb", "a\nb\nc\n", "a\nb"},
+		{"", "x\n", ""},
+		{"x\ny\n", "", "x\ny\n"},
+		{"1\n2\n3\n", "1\n3\n4\n", "0\n1\n2\n3\n"},
+	} {
+		file, err := godiffy.Compute(tc.old, tc.new, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _, err := ApplyString(tc.target, file, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if _, err := ApplyStream(&out, strings.NewReader(tc.target), file, nil); err != nil {
+			t.Fatalf("%q: expected success, got %v", tc.target, err)
+		}
+		if out.String() != want {
+			t.Errorf("%q: content = %q; want %q", tc.target, out.String(), want)
+		}
+	}
+}
+
+func TestApplyStream_Errors(t *testing.T) {
+	diff, err := godiffy.Parse(rejectTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := diff.Files[0]
+
+	// Further away than the hunk is long, which Apply would still find.
+	moved := strings.Repeat("x\n", 30) + numberLines(nil)
+	if _, _, err := ApplyString(moved, file, nil); err != nil {
+		t.Fatalf("Apply: expected success, got %v", err)
+	}
+	var out bytes.Buffer
+	_, err = ApplyStream(&out, strings.NewReader(moved), file, nil)
+	if err == nil || !strings.Contains(err.Error(), "hunk #1 does not apply") {
+		t.Errorf("expected hunk #1 to fail, got %v", err)
+	}
+
+	if _, err := ApplyStream(&out, strings.NewReader(""), file, &Options{VerifyHashes: true}); err == nil {
+		t.Error("expected error for VerifyHashes, got nil")
+	}
+}
+
+func TestApplyStream_PartialOutput(t *testing.T) {
+	diff, err := godiffy.Parse(rejectTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := diff.Files[0]
+
+	// Both fail at the second hunk, after the first was written.
+	lines := strings.SplitAfter(numberLines(map[int]string{16: "sixteen"}), "\n")
+	tests := []struct {
+		name string
+		src  io.Reader
+		want string
+	}{
+		{"hunk", strings.NewReader(strings.Join(lines, "")), "hunk #2 does not apply"},
+		{"read", io.MultiReader(strings.NewReader(strings.Join(lines[:16], "")), iotest.ErrReader(errors.New("disk gone"))), "disk gone"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		_, err := ApplyStream(&out, tt.src, file, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q error, got %v", tt.name, tt.want, err)
+		}
+		if got, want := out.String(), "1\n2\nthree\n4\n5\n6\n"; got != want {
+			t.Errorf("%s: dst = %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+func TestApplyStream_LargeInput(t *testing.T) {
+	file, err := godiffy.Compute("keep\nold\nkeep\n", "keep\nnew\nkeep\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tail := strings.Repeat("filler line\n", 100000)
+	var out bytes.Buffer
+	results, err := ApplyStream(&out, strings.NewReader("keep\nold\nkeep\n"+tail), file, nil)
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if !results[0].Applied || out.String() != "keep\nnew\nkeep\n"+tail {
+		t.Errorf("unexpected result %+v with %d bytes", results, out.Len())
+	}
+}
@@ -0,0 +1,92 @@
+package gomergy
+
+import (
+	"io"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+type Options struct {
+	VerifyHashes     bool             // check files against the index line's blob IDs before and after applying
+	Reverse          bool             // apply the diff backwards to undo it, like git apply -R
+	LineEndings      LineEndingMode   // how the line endings of the diff and the files are reconciled
+	IgnoreWhitespace bool             // match context and deleted lines ignoring changes in whitespace, like --ignore-whitespace
+	Whitespace       WhitespaceAction // what to do about whitespace errors in added lines, like --whitespace
+	Warnings         io.Writer        // where warnings are written; nil discards them
+	Reject           bool             // apply the hunks that fit and save the others to .rej files, like --reject
+	Workers          int              // files MergeToPathContext applies at once; 0 uses GOMAXPROCS
+}
+
+// LineEndingMode decides how line endings are matched and written.
+type LineEndingMode int
+
+// WhitespaceAction is what happens to added lines with whitespace errors.
+type WhitespaceAction int
+
+// WhitespaceErrorKind is a kind of whitespace error git looks for.
+type WhitespaceErrorKind int
+
+// WhitespaceError is a whitespace error in an added line.
+type WhitespaceError struct {
+	Path    string
+	Line    int // line number in the new file
+	Kind    WhitespaceErrorKind
+	Content string // the added line, without its terminator
+}
+
+// HunkResult is how one hunk of a file went.
+type HunkResult struct {
+	Applied bool
+	Line    int // 1-based line of the original content the hunk matched at
+	Offset  int // how many lines Line is after the line the hunk names; negative if before
+}
+
+// FileReject describes the hunks of one file that did not apply with
+// Options.Reject and were saved to a reject file.
+type FileReject struct {
+	Path       string
+	RejectPath string          // Path with ".rej" appended
+	Applied    int             // number of hunks that applied
+	Rejected   []int           // 1-based numbers of the rejected hunks
+	Hunks      []*godiffy.Hunk // the rejected hunks
+}
+
+// RejectError is returned when Options.Reject saved hunks to reject files.
+// Everything else in the diff has been applied.
+type RejectError struct {
+	Files []*FileReject
+}
+
+// ConflictStyle is how Merge3 writes conflicts.
+type ConflictStyle int
+
+// Merge3Options configures Merge3. The zero value writes unlabeled markers
+// of 7 characters in ConflictStyleMerge.
+type Merge3Options struct {
+	Style       ConflictStyle
+	Algorithm   godiffy.Algorithm // how each side is diffed against the base
+	OursLabel   string            // written after "<<<<<<<", like git merge-file -L
+	BaseLabel   string            // written after "|||||||"
+	TheirsLabel string            // written after ">>>>>>>"
+	MarkerSize  int               // length of the markers; 0 uses 7
+}
+
+// MergeResult is the outcome of Merge3.
+type MergeResult struct {
+	Content   string // the merged text, with conflict markers if there are conflicts
+	Conflicts []*Conflict
+}
+
+// Conflict is a region both sides changed differently. Lines keep their
+// endings. Base is the base region the conflict comes from; outside
+// ConflictStyleDiff3, where lines both sides share are moved out of
+// conflicts, it can hold more than Ours and Theirs replace.
+type Conflict struct {
+	Line        int // 1-based line of the opening marker in MergeResult.Content
+	BaseStart   int // 1-based line of base the region starts at
+	OursStart   int
+	TheirsStart int
+	Base        []string
+	Ours        []string
+	Theirs      []string
+}
@@ -0,0 +1,196 @@
+package gomergy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+// isSymlink reports whether name is a symbolic link, without following it.
+func isSymlink(name string) bool {
+	info, err := os.Lstat(name)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// readBlob returns what git stores for the file at name: its content, with
+// line endings converted as opts asks, or the target of a symbolic link.
+func readBlob(name string, opts *Options) ([]byte, error) {
+	if isSymlink(name) {
+		target, err := os.Readlink(name)
+		return []byte(target), err
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(opts.readEndings(string(data))), nil
+}
+
+// maxLinkDepth is how many links resolving a target may go through, as
+// many as Linux allows.
+const maxLinkDepth = 40
+
+// checkLinkTarget refuses link targets that are empty or that lead out of
+// the tree at path, following the links already in it.
+func checkLinkTarget(name, target, path string) error {
+	if target == "" {
+		return fmt.Errorf("empty target for symlink %s", name)
+	}
+	var dir []string
+	if parent := filepath.ToSlash(filepath.Dir(name)); parent != "." {
+		dir = strings.Split(parent, "/")
+	}
+	if _, err := resolveInTree(path, dir, target, 0); err != nil {
+		return fmt.Errorf("symlink %s points outside of the tree: %s: %w", name, target, err)
+	}
+	return nil
+}
+
+// resolveInTree follows target from the directory dir below root one name
+// at a time, through the links it meets on the way, and returns where it
+// ends up. Unlike filepath.Clean, ".." after a link goes up from where the
+// link leads. Names that do not exist yet are taken as they are.
+func resolveInTree(root string, dir []string, target string, depth int) ([]string, error) {
+	if depth > maxLinkDepth {
+		return nil, errors.New("too many levels of symbolic links")
+	}
+	if filepath.IsAbs(target) || strings.HasPrefix(target, "/") {
+		return nil, errors.New("absolute target")
+	}
+	current := slices.Clone(dir)
+	for _, name := range strings.Split(filepath.ToSlash(target), "/") {
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if len(current) == 0 {
+				return nil, errors.New("leaves the tree")
+			}
+			current = current[:len(current)-1]
+			continue
+		}
+		current = append(current, name)
+		full := filepath.Join(root, filepath.Join(current...))
+		if !isSymlink(full) {
+			continue
+		}
+		link, err := os.Readlink(full)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read symlink %s: %w", strings.Join(current, "/"), err)
+		}
+		current, err = resolveInTree(root, current[:len(current)-1], link, depth+1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return current, nil
+}
+
+// checkPath refuses a path named by a diff unless it stays inside the tree
+// at path without going through a symbolic link, which could lead
+// anywhere.
+func checkPath(name, path string) error {
+	local := filepath.FromSlash(name)
+	if !filepath.IsLocal(local) {
+		return fmt.Errorf("invalid path %q: outside of the tree", name)
+	}
+	for dir := filepath.Dir(local); dir != "."; dir = filepath.Dir(dir) {
+		if isSymlink(filepath.Join(path, dir)) {
+			return fmt.Errorf("invalid path %s: goes through symlink %s", name, filepath.ToSlash(dir))
+		}
+	}
+	return nil
+}
+
+// writeSymlink replaces whatever is at name below path with a link to
+// target.
+func writeSymlink(name, target, path string) error {
+	if err := checkLinkTarget(name, target, path); err != nil {
+		return err
+	}
+	full := filepath.Join(path, name)
+	err := os.MkdirAll(filepath.Dir(full), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(full), err)
+	}
+	if _, err := os.Lstat(full); err == nil {
+		if err := os.Remove(full); err != nil {
+			return fmt.Errorf("failed to remove file %s: %w", name, err)
+		}
+	}
+	err = os.Symlink(target, full)
+	if err != nil {
+		return fmt.Errorf("failed to create symlink %s: %w", name, err)
+	}
+	return nil
+}
+
+// handleModifiedSymlink retargets a link, or turns a link into a file and
+// back when the modes say so. Hunks are matched as opts asks, but a link
+// target is never given other line endings.
+func handleModifiedSymlink(file *godiffy.FileDiff, path string, opts *Options) error {
+	full := filepath.Join(path, file.NewPath)
+	wasLink := isSymlink(full)
+	original, err := readBlob(full, &Options{})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read file %s: %w", file.NewPath, err)
+	}
+
+	// Without a link to patch, the result is rebuilt from the hunks alone.
+	var content string
+	var failed []int
+	if err == nil {
+		var results []HunkResult
+		content, results = applyMatchingHunks(string(original), file.Hunks, opts)
+		if err := hunkError(file.Hunks, results); err != nil {
+			if !opts.Reject {
+				return fmt.Errorf("failed to apply hunks to %s: %w", file.NewPath, err)
+			}
+			failed = rejectedHunks(results)
+		}
+	} else {
+		for _, hunk := range file.Hunks {
+			for _, line := range hunk.Lines {
+				if line.Type == godiffy.HunkLineAdded || line.Type == godiffy.HunkLineContext {
+					content += line.Content
+				}
+			}
+		}
+	}
+
+	if err := writeLinkOrFile(file, content, wasLink, path); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return writeReject(file, failed, path)
+	}
+	return nil
+}
+
+// writeLinkOrFile writes content below path as the target of the link
+// file.NewPath or as its content, as the new mode says.
+func writeLinkOrFile(file *godiffy.FileDiff, content string, wasLink bool, path string) error {
+	if file.NewMode.IsSymlink() || file.NewMode == "" && wasLink {
+		return writeSymlink(file.NewPath, content, path)
+	}
+	full := filepath.Join(path, file.NewPath)
+	mode, err := file.NewMode.OSMode()
+	if err != nil {
+		return fmt.Errorf("failed to convert file mode %s: %w", file.NewMode, err)
+	}
+	if wasLink {
+		if err := os.Remove(full); err != nil {
+			return fmt.Errorf("failed to remove symlink %s: %w", file.NewPath, err)
+		}
+	}
+	err = os.WriteFile(full, []byte(content), mode)
+	if err != nil {
+		return fmt.Errorf("failed to write file %s: %w", file.NewPath, err)
+	}
+	return nil
+}
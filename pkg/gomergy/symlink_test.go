@@ -0,0 +1,275 @@
+package gomergy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+// From "git diff --cached" after retargeting, adding and deleting links
+// and replacing a file with a link.
+const symlinkTestDiff = `diff --git a/dir/up b/dir/up
+new file mode 120000
+index 0000000..f9b1b32
+--- /dev/null
++++ b/dir/up
+@@ -0,0 +1 @@
++../target.txt
+\ No newline at end of file
+diff --git a/gone b/gone
+deleted file mode 120000
+index 27fa349..0000000
+--- a/gone
++++ /dev/null
+@@ -1 +0,0 @@
+-other
+\ No newline at end of file
+diff --git a/link b/link
+index 4cbb553..5c895d0 120000
+--- a/link
++++ b/link
+@@ -1 +1 @@
+-target.txt
+\ No newline at end of file
++dir/t.txt
+\ No newline at end of file
+diff --git a/typ b/typ
+deleted file mode 100644
+index 587be6b..0000000
+--- a/typ
++++ /dev/null
+@@ -1 +0,0 @@
+-x
+diff --git a/typ b/typ
+new file mode 120000
+index 0000000..4cbb553
+--- /dev/null
++++ b/typ
+@@ -0,0 +1 @@
++target.txt
+\ No newline at end of file
+`
+
+func checkLinks(t *testing.T, dir string, links map[string]string) {
+	t.Helper()
+	for name, want := range links {
+		got, err := os.Readlink(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("failed to read link %s: %v", name, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s -> %q; want %q", name, got, want)
+		}
+	}
+}
+
+func TestMergeToPath_Symlinks(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, dir, map[string]string{"target.txt": "hi\n", "dir/t.txt": "t\n", "typ": "x\n"})
+	before := map[string]string{"link": "target.txt", "gone": "other"}
+	for name, target := range before {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	diff, err := godiffy.Parse(symlinkTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MergeToPathWithOptions(diff, dir, &Options{VerifyHashes: true}); err != nil {
+		t.Fatalf("expected symlinks to apply, got %v", err)
+	}
+	checkLinks(t, dir, map[string]string{"link": "dir/t.txt", "dir/up": "../target.txt", "typ": "target.txt"})
+	if _, err := os.Lstat(filepath.Join(dir, "gone")); !os.IsNotExist(err) {
+		t.Errorf("expected dangling link to be removed, got %v", err)
+	}
+
+	if err := MergeToPathWithOptions(diff, dir, &Options{VerifyHashes: true, Reverse: true}); err != nil {
+		t.Fatalf("expected reverse apply to succeed, got %v", err)
+	}
+	checkLinks(t, dir, before)
+	checkTree(t, filepath.Join(dir, "dir"), map[string]string{"t.txt": "t\n"})
+	if data, err := os.ReadFile(filepath.Join(dir, "typ")); err != nil || string(data) != "x\n" || isSymlink(filepath.Join(dir, "typ")) {
+		t.Errorf("typ = %q, %v; want a regular file", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "target.txt")); err != nil || string(data) != "hi\n" {
+		t.Errorf("target.txt = %q, %v; want it untouched", data, err)
+	}
+}
+
+func TestMergeToPath_SymlinkEscape(t *testing.T) {
+	for _, target := range []string{"../outside", "/etc/passwd", "dir/../../x"} {
+		dir := t.TempDir()
+		diff := godiffy.Diff{Files: []*godiffy.FileDiff{{
+			Status:  godiffy.FileStatusNew,
+			NewPath: "evil",
+			NewMode: "120000",
+			Hunks:   []*godiffy.Hunk{{Lines: []*godiffy.HunkLine{{Type: godiffy.HunkLineAdded, Content: target}}}},
+		}}}
+		err := MergeToPath(&diff, dir)
+		if err == nil || !strings.Contains(err.Error(), "points outside of the tree") {
+			t.Errorf("target %s: expected escape error, got %v", target, err)
+		}
+		if _, err := os.Lstat(filepath.Join(dir, "evil")); !os.IsNotExist(err) {
+			t.Errorf("target %s: link was created", target)
+		}
+	}
+}
+
+func TestCheckLinkTarget(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{"self": ".", "sub/up": "..", "loop": "loop"} {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name, target string
+		ok           bool
+	}{
+		{"link", "target.txt", true},
+		{"dir/up", "../target.txt", true},
+		{"a/b/c", "../../x", true},
+		{"a/b/c", "../../../x", false},
+		{"link", "..", false},
+		{"link", "", false},
+		{"link", "self/x", true},
+		{"link", "self/..", false},
+		{"link", "sub/up/sub/x", true},
+		{"link", "sub/up/..", false},
+		{"link", "loop/x", false},
+	}
+	for _, tt := range tests {
+		if err := checkLinkTarget(tt.name, tt.target, dir); (err == nil) != tt.ok {
+			t.Errorf("checkLinkTarget(%q, %q) = %v, want ok %v", tt.name, tt.target, err, tt.ok)
+		}
+	}
+}
+
+// symlinkChainDiff escapes the tree through two links that each look
+// harmless on their own.
+const symlinkChainDiff = `diff --git a/a b/a
+new file mode 120000
+--- /dev/null
++++ b/a
+@@ -0,0 +1 @@
++.
+\ No newline at end of file
+diff --git a/c b/c
+new file mode 120000
+--- /dev/null
++++ b/c
+@@ -0,0 +1 @@
++a/..
+\ No newline at end of file
+diff --git a/c/escaped.txt b/c/escaped.txt
+new file mode 100644
+--- /dev/null
++++ b/c/escaped.txt
+@@ -0,0 +1 @@
++escaped
+`
+
+func TestMergeToPath_SymlinkChain(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "root")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	diff, err := godiffy.Parse(symlinkChainDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = MergeToPath(diff, dir)
+	if err == nil || !strings.Contains(err.Error(), "points outside of the tree") {
+		t.Errorf("expected escape error, got %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(parent, "escaped.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected no file outside of the tree, got %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "c")); !os.IsNotExist(err) {
+		t.Errorf("expected link c not to be created, got %v", err)
+	}
+}
+
+func TestMergeToPath_RefusesPaths(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "root")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, dir, map[string]string{"a.txt": "a\n"})
+	if err := os.Symlink(".", filepath.Join(dir, "self")); err != nil {
+		t.Fatal(err)
+	}
+	added := []*godiffy.Hunk{{NewStart: 1, NewLineCount: 1, Lines: []*godiffy.HunkLine{{Type: godiffy.HunkLineAdded, Content: "x\n"}}}}
+	tests := []struct {
+		name string
+		file *godiffy.FileDiff
+		want string
+	}{
+		{"parent", &godiffy.FileDiff{Status: godiffy.FileStatusNew, NewPath: "../x", NewMode: "100644", Hunks: added}, "outside of the tree"},
+		{"absolute", &godiffy.FileDiff{Status: godiffy.FileStatusNew, NewPath: "/x", NewMode: "100644", Hunks: added}, "outside of the tree"},
+		{"rename source", &godiffy.FileDiff{Status: godiffy.FileStatusRenamed, OldPath: "../x", NewPath: "x"}, "outside of the tree"},
+		{"copy source", &godiffy.FileDiff{Status: godiffy.FileStatusCopied, OldPath: "../x", NewPath: "x"}, "outside of the tree"},
+		{"through link", &godiffy.FileDiff{Status: godiffy.FileStatusNew, NewPath: "self/x", NewMode: "100644", Hunks: added}, "goes through symlink self"},
+	}
+	for _, tt := range tests {
+		err := MergeToPath(&godiffy.Diff{Files: []*godiffy.FileDiff{tt.file}}, dir)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q error, got %v", tt.name, tt.want, err)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(parent, "x")); !os.IsNotExist(err) {
+		t.Errorf("expected no file outside of the tree, got %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "x")); !os.IsNotExist(err) {
+		t.Errorf("expected no file in the tree, got %v", err)
+	}
+}
+
+func TestMergeToPath_SymlinkOptions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Symlink("old  name", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	retarget := func(from, to string) *godiffy.Diff {
+		return &godiffy.Diff{Files: []*godiffy.FileDiff{{
+			Status:  godiffy.FileStatusModified,
+			OldPath: "link",
+			NewPath: "link",
+			Hunks: []*godiffy.Hunk{{OldStart: 1, OldLineCount: 1, NewStart: 1, NewLineCount: 1, Lines: []*godiffy.HunkLine{
+				{Type: godiffy.HunkLineDeleted, Content: from},
+				{Type: godiffy.HunkLineAdded, Content: to},
+			}}},
+		}}}
+	}
+
+	if err := MergeToPathWithOptions(retarget("old name", "new"), dir, &Options{IgnoreWhitespace: true}); err != nil {
+		t.Fatalf("expected hunk to match ignoring whitespace, got %v", err)
+	}
+	checkLinks(t, dir, map[string]string{"link": "new"})
+
+	err := MergeToPathWithOptions(retarget("other", "newer"), dir, &Options{Reject: true})
+	var reject *RejectError
+	if !errors.As(err, &reject) || len(reject.Files) != 1 || reject.Files[0].Path != "link" {
+		t.Fatalf("expected the hunk to be rejected, got %v", err)
+	}
+	checkLinks(t, dir, map[string]string{"link": "new"})
+	if _, err := os.Stat(filepath.Join(dir, "link.rej")); err != nil {
+		t.Errorf("expected link.rej, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package gomergy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+// tabWidth is the width git assumes for tabs when fixing indents.
+const tabWidth = 8
+
+// warn writes a warning to o.Warnings, if set.
+func (o *Options) warn(message string) {
+	if o.Warnings != nil {
+		fmt.Fprintln(o.Warnings, message)
+	}
+}
+
+func (k WhitespaceErrorKind) String() string {
+	switch k {
+	case WhitespaceTrailingSpace:
+		return "trailing whitespace"
+	case WhitespaceSpaceBeforeTab:
+		return "space before tab in indent"
+	case WhitespaceBlankAtEOF:
+		return "new blank line at EOF"
+	}
+	return "unknown whitespace error"
+}
+
+// Error formats the error the way git apply warns about it.
+func (e *WhitespaceError) Error() string {
+	return fmt.Sprintf("%s:%d: %s.\n+%s", e.Path, e.Line, e.Kind, e.Content)
+}
+
+// CheckWhitespace finds the whitespace errors git apply reports by default
+// in the added lines of diff: trailing whitespace, spaces before tabs in the
+// indent and blank lines added at the end of a file. Line endings, "\r\n"
+// included, do not count as trailing whitespace.
+func CheckWhitespace(diff *godiffy.Diff) []*WhitespaceError {
+	var errs []*WhitespaceError
+	for _, file := range diff.Files {
+		if !checksWhitespace(file) {
+			continue
+		}
+		for _, hunk := range file.Hunks {
+			line := hunk.NewStart
+			for _, hunkLine := range hunk.Lines {
+				if hunkLine.Type == godiffy.HunkLineDeleted {
+					continue
+				}
+				if hunkLine.Type == godiffy.HunkLineAdded {
+					text := hunkLine.Text()
+					if hasTrailingSpace(text) {
+						errs = append(errs, &WhitespaceError{Path: file.NewPath, Line: line, Kind: WhitespaceTrailingSpace, Content: text})
+					}
+					if hasSpaceBeforeTab(text) {
+						errs = append(errs, &WhitespaceError{Path: file.NewPath, Line: line, Kind: WhitespaceSpaceBeforeTab, Content: text})
+					}
+				}
+				line++
+			}
+		}
+		if hunk, start := blankAtEOF(file); start != -1 {
+			line := hunk.NewStart
+			for _, hunkLine := range hunk.Lines[:start] {
+				if hunkLine.Type != godiffy.HunkLineDeleted {
+					line++
+				}
+			}
+			errs = append(errs, &WhitespaceError{Path: file.NewPath, Line: line, Kind: WhitespaceBlankAtEOF, Content: hunk.Lines[start].Text()})
+		}
+	}
+	return errs
+}
+
+// fixWhitespace returns a copy of diff with the whitespace errors of its
+// added lines fixed, like git apply --whitespace=fix.
+func fixWhitespace(diff *godiffy.Diff) *godiffy.Diff {
+	result := &godiffy.Diff{Files: make([]*godiffy.FileDiff, 0, len(diff.Files))}
+	for _, file := range diff.Files {
+		if !checksWhitespace(file) {
+			result.Files = append(result.Files, file)
+			continue
+		}
+		fixed := *file
+		fixed.Hunks = make([]*godiffy.Hunk, 0, len(file.Hunks))
+		eofHunk, eofStart := blankAtEOF(file)
+		for _, hunk := range file.Hunks {
+			fixedHunk := *hunk
+			lines := hunk.Lines
+			if hunk == eofHunk {
+				lines = lines[:eofStart]
+				fixedHunk.NewLineCount -= len(hunk.Lines) - eofStart
+			}
+			fixedHunk.Lines = make([]*godiffy.HunkLine, 0, len(lines))
+			for _, line := range lines {
+				if line.Type == godiffy.HunkLineAdded {
+					line = &godiffy.HunkLine{Type: line.Type, Content: fixLine(line.Content)}
+				}
+				fixedHunk.Lines = append(fixedHunk.Lines, line)
+			}
+			fixed.Hunks = append(fixed.Hunks, &fixedHunk)
+		}
+		result.Files = append(result.Files, &fixed)
+	}
+	return result
+}
+
+// checksWhitespace reports whether the file has text lines to check.
+func checksWhitespace(file *godiffy.FileDiff) bool {
+	return !file.IsBinary && !file.IsSymlink() && !file.IsSubmodule()
+}
+
+// blankAtEOF returns the last hunk of file and the index of the first of
+// the blank lines it adds at the end of the file, or -1. A hunk that ends in
+// added lines has no context after them, so they end the file.
+func blankAtEOF(file *godiffy.FileDiff) (*godiffy.Hunk, int) {
+	if len(file.Hunks) == 0 {
+		return nil, -1
+	}
+	hunk := file.Hunks[len(file.Hunks)-1]
+	start := len(hunk.Lines)
+	for start > 0 {
+		line := hunk.Lines[start-1]
+		if line.Type != godiffy.HunkLineAdded || strings.TrimSpace(line.Text()) != "" {
+			break
+		}
+		start--
+	}
+	if start == len(hunk.Lines) {
+		return nil, -1
+	}
+	return hunk, start
+}
+
+func hasTrailingSpace(text string) bool {
+	return strings.TrimRight(text, " \t") != text
+}
+
+func hasSpaceBeforeTab(text string) bool {
+	indent := text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+	return strings.Contains(indent, " \t")
+}
+
+// fixLine removes trailing whitespace and, like git's ws_fix_copy, the
+// spaces before tabs in the indent, turning each run of tabWidth of them
+// into a tab.
+func fixLine(content string) string {
+	text := godiffy.TrimLineEnding(content)
+	ending := content[len(text):]
+	if hasSpaceBeforeTab(text) {
+		indent := text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+		last := strings.LastIndexByte(indent, '\t') + 1
+		var b strings.Builder
+		spaces := 0
+		for _, c := range []byte(text[:last]) {
+			if c != ' ' {
+				spaces = 0
+				b.WriteByte(c)
+				continue
+			}
+			spaces++
+			if spaces == tabWidth {
+				b.WriteByte('\t')
+				spaces = 0
+			}
+		}
+		b.WriteString(strings.Repeat(" ", spaces))
+		text = b.String() + text[last:]
+	}
+	return strings.TrimRight(text, " \t") + ending
+}
@@ -0,0 +1,151 @@
+package gomergy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/asdfgugus/godiffy/pkg/godiffy"
+)
+
+// From "git diff" after adding lines with whitespace errors.
+const whitespaceTestDiff = "diff --git a/a.go b/a.go\n" +
+	"index 80d5c68..8bd2178 100644\n" +
+	"--- a/a.go\n" +
+	"+++ b/a.go\n" +
+	"@@ -1,3 +1,7 @@\n" +
+	" func f() {\n" +
+	"-\treturn 1\n" +
+	"+  \tx := 1 \n" +
+	"+        \ty := 2\n" +
+	"+\treturn 1\t\n" +
+	" }\n" +
+	"+\n" +
+	"+ \n"
+
+func TestCheckWhitespace(t *testing.T) {
+	diff, err := godiffy.Parse(whitespaceTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		line int
+		kind WhitespaceErrorKind
+	}{
+		{2, WhitespaceTrailingSpace},
+		{2, WhitespaceSpaceBeforeTab},
+		{3, WhitespaceSpaceBeforeTab},
+		{4, WhitespaceTrailingSpace},
+		{7, WhitespaceTrailingSpace},
+		{6, WhitespaceBlankAtEOF},
+	}
+	errs := CheckWhitespace(diff)
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for i, w := range want {
+		if errs[i].Path != "a.go" || errs[i].Line != w.line || errs[i].Kind != w.kind {
+			t.Errorf("error %d = %s:%d %s, want a.go:%d %s", i, errs[i].Path, errs[i].Line, errs[i].Kind, w.line, w.kind)
+		}
+	}
+	if got := errs[3].Error(); got != "a.go:4: trailing whitespace.\n+\treturn 1\t" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestMergeToPathWithOptions_Whitespace(t *testing.T) {
+	diff, err := godiffy.Parse(whitespaceTestDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := "func f() {\n\treturn 1\n}\n"
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.go": original})
+	err = MergeToPathWithOptions(diff, dir, &Options{Whitespace: WhitespaceActionError})
+	var wsErr *WhitespaceError
+	if !errors.As(err, &wsErr) || !strings.Contains(err.Error(), "found 6 whitespace errors") {
+		t.Fatalf("expected whitespace errors, got %v", err)
+	}
+	checkTree(t, dir, map[string]string{"a.go": original})
+
+	var warnings strings.Builder
+	if err := MergeToPathWithOptions(diff, dir, &Options{Whitespace: WhitespaceActionWarn, Warnings: &warnings}); err != nil {
+		t.Fatalf("expected apply with warnings to succeed, got %v", err)
+	}
+	checkTree(t, dir, map[string]string{"a.go": "func f() {\n  \tx := 1 \n        \ty := 2\n\treturn 1\t\n}\n\n \n"})
+	if got := strings.Count(warnings.String(), "a.go:"); got != 6 {
+		t.Errorf("got %d warnings, want 6:\n%s", got, warnings.String())
+	}
+
+	// The result of git apply --whitespace=fix.
+	dir = t.TempDir()
+	writeTree(t, dir, map[string]string{"a.go": original})
+	if err := MergeToPathWithOptions(diff, dir, &Options{Whitespace: WhitespaceActionFix}); err != nil {
+		t.Fatalf("expected apply with fixes to succeed, got %v", err)
+	}
+	checkTree(t, dir, map[string]string{"a.go": "func f() {\n\tx := 1\n\t\ty := 2\n\treturn 1\n}\n"})
+	if diff.Files[0].Hunks[0].Lines[2].Content != "  \tx := 1 \n" {
+		t.Errorf("fixing changed the diff passed in")
+	}
+}
+
+func TestApplyHunksIgnoreWhitespace(t *testing.T) {
+	file, err := godiffy.Compute("if x {\n\ty()\n}\nz()\n", "if x {\n\ty()\n}\nw()\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := "if  x {\r\n    y()\r\n}\r\nz()\r\n"
+	if _, err := applyHunks(target, file.Hunks); err == nil {
+		t.Fatal("expected exact matching to fail")
+	}
+	got, err := applyHunksWithOptions(target, file.Hunks, &Options{IgnoreWhitespace: true})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if want := "if  x {\r\n    y()\r\n}\r\nw()\n"; got != want {
+		t.Errorf("content = %q; want %q", got, want)
+	}
+	if _, err := applyHunksWithOptions("if x {\n\tyy()\n}\nz()\n", file.Hunks, &Options{IgnoreWhitespace: true}); err == nil {
+		t.Errorf("expected a changed word not to match")
+	}
+	if _, err := applyHunksWithOptions("if x {\ny()\n}\nz()\n", file.Hunks, &Options{IgnoreWhitespace: true}); err == nil {
+		t.Errorf("expected a line without its indentation not to match")
+	}
+}
+
+// Outcomes of git apply --ignore-whitespace.
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		have, want string
+		ok         bool
+	}{
+		{"if  x {\n", "if x {\n", true},
+		{"    y()\r\n", "\ty()\n", true},
+		{"y()\n", "\ty()\n", false},
+		{"\ty()\n", "y()\n", false},
+		{"\ty()  \n", "\ty()\n", false},
+		{"z( )\n", "z()\n", false},
+		{"a b\n", "ab\n", false},
+		{"", "\n", true},
+	}
+	for _, tt := range tests {
+		if got := fuzzyMatch(tt.have, tt.want); got != tt.ok {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, want %v", tt.have, tt.want, got, tt.ok)
+		}
+	}
+}
+
+func TestFixLine(t *testing.T) {
+	for content, want := range map[string]string{
+		"  \tx := 1 \n":   "\tx := 1\n",
+		"        \ty\r\n": "\t\ty\r\n",
+		" \t \tz":         "\t\tz",
+		"ok\n":            "ok\n",
+		"  two spaces\n":  "  two spaces\n",
+	} {
+		if got := fixLine(content); got != want {
+			t.Errorf("fixLine(%q) = %q, want %q", content, got, want)
+		}
+	}
+}